@@ -1,119 +1,382 @@
 package migu
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"go/ast"
+	"go/token"
 	"strconv"
 	"strings"
+
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
 )
 
 type annotation struct {
-	Table  string
-	Option string
+	Table    string
+	Options  dialect.TableOptions
+	Option   string
+	View     bool
+	ReadOnly bool
+	History  bool
+
+	// Shards is the number of the physical tables of the struct, and Pattern is the format of their names.
+	// Shards is 0 if the struct is not sharded.
+	Shards  int
+	Pattern string
+}
+
+// tableNames returns the names of the tables of the struct of typeName.
+// The names of the sharded tables are formatted by Pattern with the shard numbers from 0,
+// or suffixed by "_<number>" if Pattern is empty.
+func (a *annotation) tableNames(typeName string) []string {
+	name := a.Table
+	if name == "" {
+		name = stringutil.ToSnakeCase(typeName)
+	}
+	if a.Shards == 0 {
+		return []string{name}
+	}
+	pattern := a.Pattern
+	if pattern == "" {
+		pattern = strings.ReplaceAll(name, "%", "%%") + "_%d"
+	}
+	names := make([]string, a.Shards)
+	for i := range names {
+		names[i] = fmt.Sprintf(pattern, i)
+	}
+	return names
+}
+
+// flagAnnotations is the keys of the annotation that can be given without the value, such as "+migu readonly".
+// They mean "true" in that case.
+var flagAnnotations = map[string]struct{}{
+	"history":  {},
+	"readonly": {},
+	"view":     {},
+}
+
+// rowFormats is the supported values of "row_format" annotation.
+var rowFormats = []string{"DEFAULT", "DYNAMIC", "FIXED", "COMPRESSED", "REDUNDANT", "COMPACT"}
+
+// annotationError is the error of the annotation at the position.
+type annotationError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *annotationError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("migu: %v: %s", e.Pos, e.Message)
+	}
+	return "migu: " + e.Message
+}
+
+// annotationPair is a "key:value" pair of the annotation.
+type annotationPair struct {
+	Key   string
+	Value string
+
+	// Flag is true if the key is given without the value.
+	Flag bool
+
+	// KeyPos and ValuePos are the positions of the key and the value in the file.
+	KeyPos   token.Pos
+	ValuePos token.Pos
 }
 
-func parseAnnotation(g *ast.CommentGroup) (*annotation, error) {
+// parseAnnotation parses the "+migu" annotation in g. It returns nil if g has no annotation.
+//
+// The annotation consists of the "key:value" pairs separated by the spaces, such as
+// `+migu table:"users" engine:"InnoDB" comment:"accounts"`. The value may be quoted by '"' with the escape sequences of Go,
+// or by '`' as is. The keys of flagAnnotations may be given without the value.
+// The pairs can be split into multiple "+migu" lines in g.
+// The positions of the errors are resolved by fset if it is not nil.
+func parseAnnotation(fset *token.FileSet, g *ast.CommentGroup) (*annotation, error) {
+	var (
+		pairs []annotationPair
+		found bool
+	)
 	for _, c := range g.List {
 		if !strings.HasPrefix(c.Text, commentPrefix) {
 			continue
 		}
-		s := strings.TrimSpace(c.Text[len(commentPrefix):])
+		text := c.Text[len(commentPrefix):]
+		offset := len(commentPrefix) + len(text) - len(strings.TrimLeft(text, " \t"))
+		s := strings.TrimSpace(text)
 		if !strings.HasPrefix(s, marker) {
 			continue
 		}
-		if len(s) == len(marker) {
-			return &annotation{}, nil
-		}
-		if !isSpace(s[len(marker)]) {
+		if len(s) > len(marker) && !isSpace(s[len(marker)]) {
 			continue
 		}
-		var a annotation
-		scanner := bufio.NewScanner(strings.NewReader(s[len(marker):]))
-		scanner.Split(splitAnnotationTags)
-		for scanner.Scan() {
-			ss := strings.SplitN(scanner.Text(), string(annotationSeparator), 2)
-			switch k, v := ss[0], ss[1]; k {
-			case "table":
-				s, err := parseString(v)
-				if err != nil {
-					return nil, fmt.Errorf("migu: BUG: %v", err)
+		found = true
+		p := &annotationParser{
+			fset: fset,
+			src:  s[len(marker):],
+			base: c.Slash + token.Pos(offset+len(marker)),
+		}
+		linePairs, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, linePairs...)
+	}
+	if !found {
+		return nil, nil
+	}
+	var a annotation
+	var options []string
+	keys := make(map[string]struct{}, len(pairs))
+	for _, pair := range pairs {
+		if _, dup := keys[pair.Key]; dup {
+			return nil, newAnnotationError(fset, pair.KeyPos, "duplicate key: %v", pair.Key)
+		}
+		keys[pair.Key] = struct{}{}
+		if pair.Flag {
+			if _, ok := flagAnnotations[pair.Key]; !ok {
+				return nil, newAnnotationError(fset, pair.KeyPos, "invalid annotation: %q expected after %q", string(annotationSeparator), pair.Key)
+			}
+			pair.Value = "true"
+		}
+		switch pair.Key {
+		case "table":
+			a.Table = pair.Value
+		case "option":
+			a.Option = pair.Value
+		case "engine", "charset", "collate":
+			if !isTableOptionName(pair.Value) {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: %v: invalid name: %q", pair.Key, pair.Value)
+			}
+			switch pair.Key {
+			case "engine":
+				a.Options.Engine = pair.Value
+			case "charset":
+				a.Options.Charset = pair.Value
+			case "collate":
+				a.Options.Collate = pair.Value
+			}
+		case "row_format":
+			rowFormat := strings.ToUpper(pair.Value)
+			if !inStrings(rowFormats, rowFormat) {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: row_format: unsupported value: %q (supported: %s)", pair.Value, strings.Join(rowFormats, ", "))
+			}
+			a.Options.RowFormat = rowFormat
+		case "shards":
+			n, err := strconv.Atoi(pair.Value)
+			if err != nil || n < 1 {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: shards: must be a positive integer: %q", pair.Value)
+			}
+			a.Shards = n
+		case "pattern":
+			if !isShardPattern(pair.Value) {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: pattern: must have a verb of the shard number such as %%d: %q", pair.Value)
+			}
+			a.Pattern = pair.Value
+		case "comment":
+			options = append(options, "COMMENT='"+strings.ReplaceAll(pair.Value, "'", "''")+"'")
+		case "view", "readonly", "history":
+			b, err := strconv.ParseBool(pair.Value)
+			if err != nil {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: %v: %v", pair.Key, err)
+			}
+			switch pair.Key {
+			case "view":
+				a.View = b
+			case "readonly":
+				a.ReadOnly = b
+			case "history":
+				a.History = b
+			}
+		default:
+			return nil, newAnnotationError(fset, pair.KeyPos, "unsupported annotation: %v", pair.Key)
+		}
+	}
+	if charset, collate := a.Options.Charset, a.Options.Collate; charset != "" && collate != "" && !strings.HasPrefix(strings.ToLower(collate), strings.ToLower(charset)+"_") {
+		for _, pair := range pairs {
+			if pair.Key == "collate" {
+				return nil, newAnnotationError(fset, pair.ValuePos, "invalid annotation: collate: %q is not the collation of charset %q", collate, charset)
+			}
+		}
+	}
+	if a.Pattern != "" && a.Shards == 0 {
+		for _, pair := range pairs {
+			if pair.Key == "pattern" {
+				return nil, newAnnotationError(fset, pair.KeyPos, "invalid annotation: pattern: shards not given")
+			}
+		}
+	}
+	if len(options) > 0 {
+		if a.Option != "" {
+			options = append([]string{a.Option}, options...)
+		}
+		a.Option = strings.Join(options, " ")
+	}
+	return &a, nil
+}
+
+// parseSpecAnnotation parses the annotation of spec in d. It returns nil if spec has no annotation.
+//
+// The doc comment of spec takes precedence over the doc comment of d, so that each spec of the grouped declaration
+// such as "type ( ... )" can have its own annotation. The annotation of d is applied to the specs that have no annotation,
+// but it cannot have the table names if it is applied to the multiple structs since they would be mapped to the same tables.
+func parseSpecAnnotation(fset *token.FileSet, d *ast.GenDecl, spec ast.Spec) (*annotation, error) {
+	if doc := specDoc(spec); doc != nil {
+		a, err := parseAnnotation(fset, doc)
+		if err != nil || a != nil {
+			return a, err
+		}
+	}
+	if d.Doc == nil {
+		return nil, nil
+	}
+	a, err := parseAnnotation(fset, d.Doc)
+	if err != nil || a == nil {
+		return a, err
+	}
+	if d.Tok == token.TYPE && (a.Table != "" || a.Pattern != "") {
+		n := 0
+		for _, spec := range d.Specs {
+			if s, ok := spec.(*ast.TypeSpec); ok {
+				if _, ok := s.Type.(*ast.StructType); !ok {
+					continue
 				}
-				a.Table = s
-			case "option":
-				s, err := parseString(v)
-				if err != nil {
-					return nil, fmt.Errorf("migu: BUG: %v", err)
+				if doc := specDoc(s); doc != nil {
+					if a, _ := parseAnnotation(fset, doc); a != nil {
+						continue
+					}
 				}
-				a.Option = s
-			default:
-				return nil, fmt.Errorf("migu: unsupported annotation: %v", k)
+				n++
 			}
 		}
-		if err := scanner.Err(); err != nil {
-			return nil, fmt.Errorf("%v: %v", err, c.Text)
+		if n > 1 {
+			return nil, newAnnotationError(fset, d.Doc.Pos(), "invalid annotation: the table name of the grouped declaration cannot be shared by %d structs; annotate each struct instead", n)
 		}
-		return &a, nil
 	}
-	return nil, nil
+	return a, nil
 }
 
-func splitAnnotationTags(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF {
-		return 0, nil, nil
+// specDoc returns the doc comment of spec.
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
 	}
-	for ; advance < len(data); advance++ {
-		if !isSpace(data[advance]) {
-			break
+	return nil
+}
+
+// isShardPattern reports whether pattern formats the distinct table names by the shard numbers.
+func isShardPattern(pattern string) bool {
+	s0, s1 := fmt.Sprintf(pattern, 0), fmt.Sprintf(pattern, 1)
+	return s0 != s1 && !strings.Contains(s0, "%!")
+}
+
+// isTableOptionName reports whether s is a valid name of the table option such as the storage engine and the charset.
+func isTableOptionName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
 		}
 	}
-	i := bytes.IndexByte(data[advance:], annotationSeparator)
-	if i < 1 {
-		return 0, nil, fmt.Errorf("migu: invalid annotation")
+	return s != ""
+}
+
+func newAnnotationError(fset *token.FileSet, pos token.Pos, format string, args ...interface{}) error {
+	e := &annotationError{
+		Message: fmt.Sprintf(format, args...),
 	}
-	advance += i + 1
-	if advance >= len(data) {
-		return 0, nil, fmt.Errorf("migu: invalid annotation")
+	if fset != nil {
+		e.Pos = fset.Position(pos)
 	}
-	switch quote := data[advance]; quote {
-	case '"':
-		for advance++; advance < len(data); advance++ {
-			i := bytes.IndexByte(data[advance:], quote)
-			if i < 0 {
-				break
+	return e
+}
+
+// annotationParser parses the "key:value" pairs of a line of the annotation.
+type annotationParser struct {
+	fset *token.FileSet
+	src  string
+
+	// base is the position of the beginning of src.
+	base token.Pos
+}
+
+func (p *annotationParser) parse() ([]annotationPair, error) {
+	var pairs []annotationPair
+	for i := 0; ; {
+		for i < len(p.src) && isSpace(p.src[i]) {
+			i++
+		}
+		if i >= len(p.src) {
+			return pairs, nil
+		}
+		keyStart := i
+		for i < len(p.src) && p.src[i] != annotationSeparator && !isSpace(p.src[i]) {
+			i++
+		}
+		if i == keyStart {
+			return nil, p.errorf(i, "invalid annotation: key not given")
+		}
+		key := p.src[keyStart:i]
+		if i >= len(p.src) || p.src[i] != annotationSeparator {
+			pairs = append(pairs, annotationPair{
+				Key:    key,
+				Flag:   true,
+				KeyPos: p.base + token.Pos(keyStart),
+			})
+			continue
+		}
+		i++
+		valueStart := i
+		if i >= len(p.src) || isSpace(p.src[i]) {
+			return nil, p.errorf(i, "invalid annotation: value not given")
+		}
+		var value string
+		switch quote := p.src[i]; quote {
+		case '"', '`':
+			end := p.closingQuote(i)
+			if end < 0 {
+				return nil, p.errorf(valueStart, "invalid annotation: string not terminated")
 			}
-			advance += i
-			if data[advance-1] != '\\' {
-				return advance + 1, bytes.TrimSpace(data[:advance+1]), nil
+			s, err := strconv.Unquote(p.src[i : end+1])
+			if err != nil {
+				return nil, p.errorf(valueStart, "invalid annotation: %v: %s", err, p.src[i:end+1])
 			}
-		}
-		return 0, nil, fmt.Errorf("migu: invalid annotation: string not terminated")
-	case '`':
-		for advance++; advance < len(data); advance++ {
-			i := bytes.IndexByte(data[advance:], quote)
-			if i < 0 {
-				break
+			value, i = s, end+1
+			if i < len(p.src) && !isSpace(p.src[i]) {
+				return nil, p.errorf(i, "invalid annotation: unexpected %q after the value", p.src[i])
 			}
-			advance += i
-			return advance + 1, bytes.TrimSpace(data[:advance+1]), nil
+		default:
+			for i < len(p.src) && !isSpace(p.src[i]) {
+				i++
+			}
+			value = p.src[valueStart:i]
 		}
-		return 0, nil, fmt.Errorf("migu: invalid annotation: string not terminated")
-	}
-	if isSpace(data[advance]) {
-		return 0, nil, fmt.Errorf("migu: invalid annotation: value not given")
+		pairs = append(pairs, annotationPair{
+			Key:      key,
+			Value:    value,
+			KeyPos:   p.base + token.Pos(keyStart),
+			ValuePos: p.base + token.Pos(valueStart),
+		})
 	}
-	for advance++; advance < len(data); advance++ {
-		if isSpace(data[advance]) {
-			return advance, bytes.TrimSpace(data[:advance]), nil
+}
+
+// closingQuote returns the index of the quote that closes the string that starts at start, or -1 if it is not terminated.
+func (p *annotationParser) closingQuote(start int) int {
+	quote := p.src[start]
+	for i := start + 1; i < len(p.src); i++ {
+		switch p.src[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i
 		}
 	}
-	return advance, bytes.TrimSpace(data[:advance]), nil
+	return -1
 }
 
-func parseString(s string) (string, error) {
-	if b := s[0]; b == '"' || b == '`' {
-		return strconv.Unquote(s)
-	}
-	return s, nil
+func (p *annotationParser) errorf(i int, format string, args ...interface{}) error {
+	return newAnnotationError(p.fset, p.base+token.Pos(i), format, args...)
 }
@@ -0,0 +1,77 @@
+package migu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naoina/migu/dialect"
+)
+
+const (
+	// archiveTablePrefix is the prefix of the names of the tables archived by WithArchive.
+	archiveTablePrefix = "_migu_trash_"
+
+	archiveTimeFormat = "20060102150405"
+
+	// archiveHashLength is the length of the hash that makes the truncated name of the archive table unique.
+	archiveHashLength = 8
+)
+
+func isArchiveTable(name string) bool {
+	return strings.HasPrefix(name, archiveTablePrefix)
+}
+
+// archiveTableName returns the name of the table that archives names at t.
+// If the name is longer than maxIdentifierLength, names are truncated and followed by the hash of them.
+func archiveTableName(t time.Time, names ...string) string {
+	name := strings.Join(names, "_")
+	suffix := "_" + t.UTC().Format(archiveTimeFormat)
+	if len(archiveTablePrefix)+len(name)+len(suffix) > maxIdentifierLength {
+		sum := sha256.Sum256([]byte(name))
+		hash := hex.EncodeToString(sum[:])[:archiveHashLength]
+		name = strings.TrimRight(name[:maxIdentifierLength-len(archiveTablePrefix)-len(suffix)-len(hash)-1], "_") + "_" + hash
+	}
+	return archiveTablePrefix + name + suffix
+}
+
+// archivedAt returns the time when the table named name was archived.
+func archivedAt(name string) (time.Time, bool) {
+	if !isArchiveTable(name) || len(name) < len(archiveTimeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(archiveTimeFormat, name[len(name)-len(archiveTimeFormat):])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// PurgeArchives drops the tables that were archived by WithArchive before the time.
+// It returns the names of the dropped tables. Each table is dropped on its own because DROP TABLE causes
+// an implicit commit, so that the names of the tables dropped so far are returned with the error if it fails.
+func PurgeArchives(d dialect.Dialect, before time.Time) ([]string, error) {
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range tableMap {
+		if t, ok := archivedAt(name); ok && t.Before(before) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var dropped []string
+	for _, name := range names {
+		for _, sql := range d.DropTableSQL(dialect.Table{Name: name}) {
+			if _, err := execInTransaction(d, sql, nil); err != nil {
+				return dropped, err
+			}
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
@@ -0,0 +1,49 @@
+package migu
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// DefaultAuditTable is the name of the audit table used by WithAudit.
+const DefaultAuditTable = "migu_audit_log"
+
+// AuditMetadata is the metadata of the caller that is recorded with each applied statement by WithAudit option.
+type AuditMetadata struct {
+	User       string
+	Host       string
+	AppVersion string
+}
+
+// CurrentAuditMetadata returns AuditMetadata that has the user and the host name of the current process, and appVersion.
+// The user and the host name are left empty if they cannot be retrieved.
+func CurrentAuditMetadata(appVersion string) AuditMetadata {
+	metadata := AuditMetadata{
+		AppVersion: appVersion,
+	}
+	if u, err := user.Current(); err == nil {
+		metadata.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		metadata.Host = host
+	}
+	return metadata
+}
+
+// AuditLog returns the records of the statements that were applied to the column of the table in order of application,
+// from the audit table recorded by WithAudit or WithAuditTable option.
+// The records of all columns of the table are returned if column is empty, and the records of all tables if table is also empty.
+// If auditTable is empty, DefaultAuditTable is used.
+func AuditLog(d dialect.Dialect, auditTable, table, column string) ([]dialect.Audit, error) {
+	a, ok := d.(dialect.AuditStore)
+	if !ok {
+		return nil, fmt.Errorf("migu: the dialect does not support the audit table")
+	}
+	if auditTable == "" {
+		auditTable = DefaultAuditTable
+	}
+	return a.Audits(auditTable, table, column)
+}
@@ -0,0 +1,98 @@
+package migu
+
+import (
+	"sync"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// SchemaCache caches the column schemas of the database that are retrieved by Diff, Sync and Plan.
+// It is useful for the services that compare the schema periodically, such as drift watchers and health checks,
+// because they do not query the database until the cache is invalidated.
+//
+// The schemas are cached per table. A cache must not be shared by the dialects of the different databases.
+// Sync invalidates the tables that are changed by the executed statements, but the changes made by
+// the others are not detected, so call Invalidate explicitly when the schema may be changed.
+// SchemaCache is safe for concurrent use.
+type SchemaCache struct {
+	mu     sync.Mutex
+	tables map[string][]dialect.ColumnSchema
+	all    bool
+}
+
+// NewSchemaCache returns a new empty SchemaCache.
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{
+		tables: map[string][]dialect.ColumnSchema{},
+	}
+}
+
+// Invalidate removes the tables from the cache.
+// If no tables are given, Invalidate removes all of the tables.
+func (c *SchemaCache) Invalidate(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(tables) == 0 {
+		c.tables = map[string][]dialect.ColumnSchema{}
+	}
+	for _, name := range tables {
+		delete(c.tables, name)
+	}
+	c.all = false
+}
+
+// tableMap returns the column schemas of the tables in the same way as getTableMap.
+// Only the tables that are not cached are retrieved from the database.
+// The tables that do not exist in the database are also cached as the tables that have no columns.
+func (c *SchemaCache) tableMap(d dialect.Dialect, tables ...string) (map[string][]dialect.ColumnSchema, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(tables) == 0 {
+		if !c.all {
+			tableMap, err := getTableMap(d)
+			if err != nil {
+				return nil, err
+			}
+			c.tables, c.all = tableMap, true
+		}
+		return c.copyTables(nil), nil
+	}
+	if !c.all {
+		var missing []string
+		for _, name := range tables {
+			if _, ok := c.tables[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			tableMap, err := getTableMap(d, missing...)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range missing {
+				c.tables[name] = tableMap[name]
+			}
+		}
+	}
+	return c.copyTables(tables), nil
+}
+
+// copyTables returns the copy of the cached tables that have names, or all of them if names is nil.
+// The tables that have no columns are not contained.
+func (c *SchemaCache) copyTables(names []string) map[string][]dialect.ColumnSchema {
+	tableMap := map[string][]dialect.ColumnSchema{}
+	if names == nil {
+		for name, schemas := range c.tables {
+			if len(schemas) > 0 {
+				tableMap[name] = schemas
+			}
+		}
+		return tableMap
+	}
+	for _, name := range names {
+		if schemas := c.tables[name]; len(schemas) > 0 {
+			tableMap[name] = schemas
+		}
+	}
+	return tableMap
+}
@@ -0,0 +1,69 @@
+package migu
+
+import "strings"
+
+// foldNameCase returns the tables of oldMap whose names are replaced with the names in newMap that are equal under
+// case-folding, so that diffTables matches the tables, the columns and the indexes case-insensitively.
+// The names that exactly match are preferred, and the others are left as they are.
+func foldNameCase(oldMap, newMap map[string]*table) map[string]*table {
+	newNames := make(map[string]string, len(newMap))
+	for name := range newMap {
+		newNames[strings.ToLower(name)] = name
+	}
+	folded := make(map[string]*table, len(oldMap))
+	for name, tbl := range oldMap {
+		if _, exists := newMap[name]; !exists {
+			if newName, ok := newNames[strings.ToLower(name)]; ok {
+				if _, exists := oldMap[newName]; !exists {
+					name = newName
+				}
+			}
+		}
+		if newTbl := newMap[name]; newTbl != nil {
+			tbl = foldFieldCase(name, tbl, newTbl)
+		}
+		folded[name] = tbl
+	}
+	return folded
+}
+
+// foldFieldCase returns the copy of tbl whose columns and indexes are renamed to the ones of newTbl that are equal under case-folding.
+func foldFieldCase(name string, tbl, newTbl *table) *table {
+	columns := make(map[string]string, len(newTbl.Fields))
+	indexes := map[string]string{}
+	for _, f := range newTbl.Fields {
+		columns[strings.ToLower(f.Column)] = f.Column
+		for _, index := range append(f.Indexes(), f.UniqueIndexes()...) {
+			indexes[strings.ToLower(index)] = index
+		}
+	}
+	foldName := func(names map[string]string, s string) string {
+		if folded, ok := names[strings.ToLower(s)]; ok {
+			return folded
+		}
+		return s
+	}
+	folded := *tbl
+	folded.Fields = make([]*field, len(tbl.Fields))
+	for i, f := range tbl.Fields {
+		ff := *f
+		ff.Table = name
+		ff.Column = foldName(columns, f.Column)
+		ff.RawIndexes = make([]string, len(f.RawIndexes))
+		for j, index := range f.RawIndexes {
+			ff.RawIndexes[j] = foldName(indexes, index)
+		}
+		ff.RawUniques = make([]string, len(f.RawUniques))
+		for j, index := range f.RawUniques {
+			ff.RawUniques[j] = foldName(indexes, index)
+		}
+		if f.IndexPositions != nil {
+			ff.IndexPositions = make(map[string]int, len(f.IndexPositions))
+			for index, pos := range f.IndexPositions {
+				ff.IndexPositions[foldName(indexes, index)] = pos
+			}
+		}
+		folded.Fields[i] = &ff
+	}
+	return &folded
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// defaultConfigFile is the config file that is read if it exists in the current directory and --config is not given.
+const defaultConfigFile = "migu.yaml"
+
+// loadConfig sets the options of cmd from the config file.
+// The keys of the top level are the global options, and the keys in the section named after a command
+// (e.g. "sync:" or "dump:") are the options of the command. The options given on the command line take precedence.
+//
+//	type: mysql
+//	host: db.example.com
+//	sync:
+//	  disallow-safety: [destructive, data-lossy]
+//	  pre-sql: ["SET SESSION lock_wait_timeout = 5"]
+//	dump:
+//	  dir: models
+//	  include: ["^user"]
+func loadConfig(cmd *cobra.Command, fname string) error {
+	explicit := fname != ""
+	if !explicit {
+		fname = defaultConfigFile
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer f.Close()
+	var config map[string]interface{}
+	// The empty config file has no options.
+	if err := yaml.NewDecoder(f, yaml.DisallowDuplicateKey()).Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to decode config file: %w", err)
+	}
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		section, ok := config[key].(map[string]interface{})
+		if !ok {
+			if cmd.Root().PersistentFlags().Lookup(key) == nil {
+				return fmt.Errorf("unknown global option in config file: %s", key)
+			}
+			if err := setFlagFromConfig(cmd.Flags(), key, config[key]); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasCommand(cmd.Root(), key) {
+			return fmt.Errorf("unknown command in config file: %s", key)
+		}
+		if key != cmd.Name() {
+			continue
+		}
+		names := make([]string, 0, len(section))
+		for name := range section {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if cmd.LocalNonPersistentFlags().Lookup(name) == nil {
+				return fmt.Errorf("unknown option of %s command in config file: %s", key, name)
+			}
+			if err := setFlagFromConfig(cmd.Flags(), name, section[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// flagAliases is the pairs of the flags that set the same option.
+var flagAliases = map[string]string{
+	"type":    "dialect",
+	"dialect": "type",
+}
+
+// setFlagFromConfig sets value to the flag of name unless it or its alias is given on the command line.
+// Each element is set in order if value is a list.
+func setFlagFromConfig(flags *pflag.FlagSet, name string, value interface{}) error {
+	if flags.Changed(name) {
+		return nil
+	}
+	if alias, ok := flagAliases[name]; ok && flags.Changed(alias) {
+		return nil
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	for _, v := range values {
+		if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("invalid value of %s in config file: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func hasCommand(root *cobra.Command, name string) bool {
+	for _, cmd := range root.Commands() {
+		if cmd.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestLoadConfig(t *testing.T) {
+	for _, v := range []struct {
+		i      int
+		config string
+		args   []string
+		expect map[string]string
+		err    string
+	}{
+		{1, "", nil, map[string]string{
+			"type":    "mysql",
+			"host":    "",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{2, "host: db.example.com\n", nil, map[string]string{
+			"type":    "mysql",
+			"host":    "db.example.com",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{3, "host: db.example.com\n", []string{"--host", "localhost"}, map[string]string{
+			"type":    "mysql",
+			"host":    "localhost",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{4, "dialect: spanner\n", nil, map[string]string{
+			"type":    "spanner",
+			"host":    "",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{5, "type: mariadb\n", []string{"--dialect", "spanner"}, map[string]string{
+			"type":    "spanner",
+			"host":    "",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{6, "dialect: spanner\n", []string{"--type", "mariadb"}, map[string]string{
+			"type":    "mariadb",
+			"host":    "",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{7, "sync:\n  include: [\"^user\", \"^post\"]\n  pre-sql: [\"SET a = 1\", \"SET b = 2\"]\n  dry-run: true\n", nil, map[string]string{
+			"type":    "mysql",
+			"host":    "",
+			"include": "[^user,^post]",
+			"pre-sql": "[SET a = 1,SET b = 2]",
+			"dry-run": "true",
+		}, ""},
+		{8, "sync:\n  include: [\"^user\", \"^post\"]\n", []string{"--include", "^admin"}, map[string]string{
+			"type":    "mysql",
+			"host":    "",
+			"include": "[^admin]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{9, "dump:\n  dir: models\n", nil, map[string]string{
+			"type":    "mysql",
+			"host":    "",
+			"include": "[]",
+			"pre-sql": "[]",
+			"dry-run": "false",
+		}, ""},
+		{10, "unknown: 1\n", nil, nil, "unknown global option in config file: unknown"},
+		{11, "unknown:\n  dir: models\n", nil, nil, "unknown command in config file: unknown"},
+		{12, "sync:\n  dir: models\n", nil, nil, "unknown option of sync command in config file: dir"},
+		{13, "sync:\n  dry-run: maybe\n", nil, nil, "invalid value of dry-run in config file: invalid argument \"maybe\" for \"--dry-run\" flag: strconv.ParseBool: parsing \"maybe\": invalid syntax"},
+		{14, "type: mysql\ntype: spanner\n", nil, nil, "failed to decode config file: "},
+	} {
+		v := v
+		t.Run(fmt.Sprintf("%v", v.i), func(t *testing.T) {
+			cmd := newConfigTestCommand()
+			fname := filepath.Join(t.TempDir(), "migu.yaml")
+			if err := os.WriteFile(fname, []byte(v.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := cmd.ParseFlags(v.args); err != nil {
+				t.Fatal(err)
+			}
+			err := loadConfig(cmd, fname)
+			if v.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), v.err) {
+					t.Fatalf("loadConfig(...) error = %v; want %v", err, v.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual := map[string]string{}
+			for name := range v.expect {
+				actual[name] = cmd.Flags().Lookup(name).Value.String()
+			}
+			if diff := cmp.Diff(actual, v.expect); diff != "" {
+				t.Errorf("(-got +want)\n%v", diff)
+			}
+		})
+	}
+	t.Run("missing file", func(t *testing.T) {
+		cmd := newConfigTestCommand()
+		err := loadConfig(cmd, filepath.Join(t.TempDir(), "migu.yaml"))
+		if err == nil {
+			t.Fatalf("loadConfig(...) error = nil; want the error of the missing file given by --config")
+		}
+	})
+}
+
+// newConfigTestCommand returns sync command under the root command, which have the subset of the flags of migu.
+func newConfigTestCommand() *cobra.Command {
+	var databaseType string
+	root := &cobra.Command{Use: progName}
+	root.PersistentFlags().StringVar(&databaseType, "type", databaseTypeMySQL, "")
+	root.PersistentFlags().StringVar(&databaseType, "dialect", databaseTypeMySQL, "")
+	root.PersistentFlags().String("host", "", "")
+	sync := &cobra.Command{Use: "sync"}
+	sync.Flags().StringSlice("include", nil, "")
+	sync.Flags().StringArray("pre-sql", nil, "")
+	sync.Flags().Bool("dry-run", false, "")
+	dump := &cobra.Command{Use: "dump"}
+	dump.Flags().String("dir", "", "")
+	root.AddCommand(sync, dump)
+	return sync
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/howeyc/gopass"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// tlsConfigName is the name of the TLS config that is registered to the MySQL driver by --ssl-* flags.
+	tlsConfigName = "migu"
+
+	// sshNetwork is the network that is registered to the MySQL driver to connect through the SSH tunnel of --ssh-host.
+	sshNetwork = "migu-ssh"
+
+	sslModeDisabled       = "disabled"
+	sslModeRequired       = "required"
+	sslModeVerifyCA       = "verify-ca"
+	sslModeVerifyIdentity = "verify-identity"
+)
+
+// configureConnection applies the TLS and the SSH tunnel options to config.
+func configureConnection(config *mysql.Config) error {
+	if err := configureTLS(config); err != nil {
+		return err
+	}
+	return configureSSHTunnel(config)
+}
+
+// configureTLS registers the TLS config of --ssl-* flags and uses it for config.
+// The default of --ssl-mode is verify-identity if --ssl-ca is given, required if --ssl-cert is given, and disabled otherwise.
+func configureTLS(config *mysql.Config) error {
+	opt := option.mysql
+	mode := strings.ToLower(strings.ReplaceAll(opt.SSLMode, "_", "-"))
+	if mode == "" {
+		switch {
+		case opt.SSLCA != "":
+			mode = sslModeVerifyIdentity
+		case opt.SSLCert != "":
+			mode = sslModeRequired
+		default:
+			mode = sslModeDisabled
+		}
+	}
+	if mode == sslModeDisabled {
+		return nil
+	}
+	tlsConfig := &tls.Config{}
+	if opt.SSLCA != "" {
+		pem, err := os.ReadFile(opt.SSLCA)
+		if err != nil {
+			return fmt.Errorf("failed to read --ssl-ca: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in --ssl-ca: %s", opt.SSLCA)
+		}
+	}
+	if opt.SSLCert != "" || opt.SSLKey != "" {
+		if opt.SSLCert == "" || opt.SSLKey == "" {
+			return fmt.Errorf("--ssl-cert and --ssl-key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(opt.SSLCert, opt.SSLKey)
+		if err != nil {
+			return fmt.Errorf("failed to load the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	switch mode {
+	case sslModeRequired:
+		tlsConfig.InsecureSkipVerify = true
+	case sslModeVerifyCA:
+		// The certificate chain is verified without the host name, since the server certificates often lack the name of the host.
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = verifyCertificateChain(tlsConfig.RootCAs)
+	case sslModeVerifyIdentity:
+		// do nothing.
+	default:
+		return fmt.Errorf("unknown --ssl-mode: %s (supported: %s, %s, %s, %s)", opt.SSLMode, sslModeDisabled, sslModeRequired, sslModeVerifyCA, sslModeVerifyIdentity)
+	}
+	if err := mysql.RegisterTLSConfig(tlsConfigName, tlsConfig); err != nil {
+		return err
+	}
+	config.TLSConfig = tlsConfigName
+	return nil
+}
+
+// verifyCertificateChain returns the function that verifies the certificate chain of the server by roots without the host name.
+func verifyCertificateChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("no server certificates")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+		})
+		return err
+	}
+}
+
+// configureSSHTunnel connects to the host of --ssh-host and makes config connect to the database through it.
+// The address of the database is resolved on the SSH host, so "localhost" means the SSH host itself.
+func configureSSHTunnel(config *mysql.Config) error {
+	opt := option.mysql
+	if opt.SSHHost == "" {
+		return nil
+	}
+	if config.Net == "unix" {
+		return fmt.Errorf("--ssh-host cannot be used with --protocol=socket")
+	}
+	username, addr := parseSSHHost(opt.SSHHost)
+	auth, err := sshAuthMethods(opt.SSHKey)
+	if err != nil {
+		return err
+	}
+	hostKeyCallback, err := knownhosts.New(opt.SSHKnownHosts)
+	if err != nil {
+		return fmt.Errorf("failed to read --ssh-known-hosts: %w", err)
+	}
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to the SSH host %s: %w", addr, err)
+	}
+	// The client is kept open until the process exits.
+	mysql.RegisterDialContext(sshNetwork, func(ctx context.Context, addr string) (net.Conn, error) {
+		return client.Dial("tcp", addr)
+	})
+	config.Net = sshNetwork
+	if config.Addr == "" {
+		config.Addr = "localhost"
+	}
+	if _, _, err := net.SplitHostPort(config.Addr); err != nil {
+		config.Addr = net.JoinHostPort(config.Addr, "3306")
+	}
+	return nil
+}
+
+// parseSSHHost returns the user name and the address of "[USER@]HOST[:PORT]".
+// The user name is the current user and the port is 22 if they are omitted.
+func parseSSHHost(s string) (username, addr string) {
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		username, s = s[:i], s[i+1:]
+	}
+	if username == "" {
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		s = net.JoinHostPort(s, "22")
+	}
+	return username, s
+}
+
+// sshAuthMethods returns the authentication methods of the private key of keyFile if given, or the SSH agent of $SSH_AUTH_SOCK otherwise.
+// The passphrase of the private key is asked from the tty if needed.
+func sshAuthMethods(keyFile string) ([]ssh.AuthMethod, error) {
+	if keyFile == "" {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, fmt.Errorf("--ssh-key is required if SSH agent is not running")
+		}
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to SSH agent: %w", err)
+		}
+		return []ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(conn).Signers)}, nil
+	}
+	pem, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --ssh-key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(pem)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase, perr := gopass.GetPasswdPrompt(fmt.Sprintf("Enter passphrase for %s: ", keyFile), false, os.Stdin, os.Stderr)
+		if perr != nil {
+			return nil, perr
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --ssh-key: %w", err)
+	}
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// defaultKnownHosts returns the known_hosts file of the current user.
+func defaultKnownHosts() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/naoina/migu"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// printDiff writes ops grouped by the table in the style of the unified diff.
+// The statements that create are marked by "+", that drop are marked by "-", and the others are marked by "~".
+// The tables are written in order of their first statements.
+func printDiff(w io.Writer, ops []migu.Operation, color bool) {
+	var tables []string
+	groups := map[string][]migu.Operation{}
+	for _, op := range ops {
+		if _, ok := groups[op.Table]; !ok {
+			tables = append(tables, op.Table)
+		}
+		groups[op.Table] = append(groups[op.Table], op)
+	}
+	paint := func(c, s string) string {
+		if !color {
+			return s
+		}
+		return c + s + colorReset
+	}
+	for _, table := range tables {
+		fmt.Fprintln(w, paint(colorCyan, fmt.Sprintf("@@ %s @@", table)))
+		for _, op := range groups[table] {
+			marker, c := diffMarker(op.Kind)
+			for _, line := range strings.Split(op.SQL, "\n") {
+				fmt.Fprintln(w, paint(c, marker+" "+line))
+			}
+		}
+	}
+}
+
+func diffMarker(kind migu.OperationKind) (marker, color string) {
+	switch kind {
+	case migu.OperationCreateTable, migu.OperationAddColumn, migu.OperationCreateIndex, migu.OperationSeed:
+		return "+", colorGreen
+	case migu.OperationDropTable, migu.OperationDropColumn, migu.OperationDropIndex, migu.OperationArchive, migu.OperationPruneSeed:
+		return "-", colorRed
+	}
+	return "~", colorYellow
+}
+
+// useColor reports whether the output to f should be colored by mode (auto, always or never).
+// In auto mode, the output is colored if f is a terminal and $NO_COLOR is not set.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return fi.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("unknown color mode: %s", mode)
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
-	"path"
+	"text/template"
 
 	"github.com/naoina/migu"
 	"github.com/naoina/migu/dialect"
@@ -19,11 +19,65 @@ func init() {
 			return dump.Execute(args, option)
 		},
 	}
+	dumpCmd.Flags().StringVar(&dump.Dir, "dir", "", "Write Go code into DIRECTORY per table")
+	dumpCmd.Flags().BoolVar(&dump.Merge, "merge", false, "Merge Go code into the existing files in the directory of --dir")
+	dumpCmd.Flags().StringVar(&dump.Template, "template", "", "Render each table by the text/template in FILE")
+	dumpCmd.Flags().StringVar(&dump.Package, "package", "", "Package name of Go code")
+	dumpCmd.Flags().StringVar(&dump.Header, "header", "", "Header comment of Go code")
+	dumpCmd.Flags().StringVar(&dump.JSONTag, "json-tag", "", "Add json tags in STYLE (snake, lower-camel or upper-camel)")
+	dumpCmd.Flags().StringVar(&dump.DBTag, "db-tag", "", "Add db tags for sqlx in STYLE (snake, lower-camel or upper-camel)")
+	dumpCmd.Flags().BoolVar(&dump.GormTag, "gorm-tag", false, "Add gorm tags")
+	dumpCmd.Flags().StringVar(&dump.Nullable, "nullable", "", "Generate the types of nullable columns in STYLE (default, pointer, sql-null or sql-null-generic)")
+	dumpCmd.Flags().StringVar(&dump.Views, "views", "", "Treat the views in MODE (table, skip or readonly)")
+	dumpCmd.Flags().BoolVar(&dump.SkipUnsupported, "skip-unsupported-types", false, "Skip the columns of unsupported data types with warnings")
+	dumpCmd.Flags().StringSliceVar(&dump.Includes, "include", nil, "Dump only the tables that match PATTERN (regular expression, can be specified multiple times)")
+	dumpCmd.Flags().StringSliceVar(&dump.Excludes, "exclude", nil, "Skip the tables that match PATTERN (regular expression, can be specified multiple times)")
+	dumpCmd.Flags().StringSliceVar(&dump.Only, "only", nil, "Same as --include")
+	dumpCmd.Flags().StringSliceVar(&dump.Except, "except", nil, "Same as --exclude")
+	dumpCmd.Flags().BoolVar(&dump.Singular, "singular", false, "Singularize the table names for the struct names")
+	dumpCmd.Flags().StringSliceVar(&dump.TrimPrefixes, "trim-prefix", nil, "Remove PREFIX from the table names for the struct names (can be specified multiple times)")
+	dumpCmd.Flags().BoolVar(&dump.ColumnConstants, "column-constants", false, "Generate the constants of the column names")
+	dumpCmd.Flags().BoolVar(&dump.TableNameConstants, "table-name-constants", false, "Generate the constants of the table names")
+	dumpCmd.Flags().BoolVar(&dump.CRUD, "crud", false, "Generate the CRUD helpers that use database/sql")
+	dumpCmd.Flags().BoolVar(&dump.TableNameMethod, "table-name-method", false, "Generate TableName methods")
+	dumpCmd.Flags().StringSliceVar(&dump.Imports, "import", nil, "Extra import path of Go code (can be specified multiple times)")
+	dumpCmd.Flags().StringVar(&dump.SQLDir, "sql-dir", "", "Write CREATE TABLE statements into DIRECTORY per table instead of Go code")
+	dumpCmd.Flags().StringVar(&dump.MarkdownDir, "markdown-dir", "", "Write Markdown documents into DIRECTORY per table instead of Go code")
 	dumpCmd.SetUsageTemplate(usageTemplate + "\nWith FILE, output to FILE.\n")
 	rootCmd.AddCommand(dumpCmd)
 }
 
-type dump struct{}
+type dump struct {
+	Dir      string
+	Merge    bool
+	Template string
+	SQLDir   string
+	Package  string
+	Header   string
+	Imports  []string
+	JSONTag  string
+	DBTag    string
+	GormTag  bool
+	Nullable string
+	Views    string
+
+	SkipUnsupported bool
+
+	Includes []string
+	Excludes []string
+	Only     []string
+	Except   []string
+
+	MarkdownDir string
+
+	Singular     bool
+	TrimPrefixes []string
+
+	ColumnConstants    bool
+	TableNameConstants bool
+	TableNameMethod    bool
+	CRUD               bool
+}
 
 func (d *dump) Execute(args []string, opt *Option) error {
 	var dbname string
@@ -38,28 +92,108 @@ func (d *dump) Execute(args []string, opt *Option) error {
 	default:
 		return fmt.Errorf("too many arguments")
 	}
-	var opts []dialect.Option
-	if columnTypes := opt.global.ColumnTypes; len(columnTypes) != 0 {
-		opts = append(opts, dialect.WithColumnType(columnTypes))
-	}
-	var di dialect.Dialect
-	switch typ := opt.global.DatabaseType; typ {
-	case databaseTypeMySQL, databaseTypeMariaDB:
-		db, err := openDatabase(dbname)
-		if err != nil {
-			return err
-		}
-		defer db.Close()
-		di = dialect.NewMySQL(db, opts...)
-	case databaseTypeSpanner:
-		di = dialect.NewSpanner(path.Join("projects", opt.spanner.Project, "instances", opt.spanner.Instance, "databases", dbname), opts...)
-	default:
-		return fmt.Errorf("BUG: unknown database type: %s", typ)
+	di, closeDB, err := openDialect(opt, dbname)
+	if err != nil {
+		return err
 	}
+	defer closeDB()
 	return d.run(di, filename)
 }
 
 func (d *dump) run(di dialect.Dialect, filename string) error {
+	var opts []migu.Option
+	if includes := append(d.Includes, d.Only...); len(includes) > 0 {
+		opts = append(opts, migu.WithIncludeTables(includes...))
+	}
+	if excludes := append(d.Excludes, d.Except...); len(excludes) > 0 {
+		opts = append(opts, migu.WithExcludeTables(excludes...))
+	}
+	if d.SQLDir != "" {
+		return migu.DumpSQLDir(di, d.SQLDir, opts...)
+	}
+	if d.MarkdownDir != "" {
+		return migu.DumpMarkdownDir(di, d.MarkdownDir, opts...)
+	}
+	if d.Package != "" {
+		opts = append(opts, migu.WithPackageName(d.Package))
+	}
+	if d.Header != "" {
+		opts = append(opts, migu.WithHeader(d.Header))
+	}
+	if len(d.Imports) > 0 {
+		opts = append(opts, migu.WithImports(d.Imports...))
+	}
+	if d.JSONTag != "" {
+		style, err := migu.ParseNamingStyle(d.JSONTag)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithJSONTag(style))
+	}
+	if d.DBTag != "" {
+		style, err := migu.ParseNamingStyle(d.DBTag)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithDBTag(style))
+	}
+	if d.GormTag {
+		opts = append(opts, migu.WithGormTag())
+	}
+	if d.Nullable != "" {
+		style, err := migu.ParseNullableStyle(d.Nullable)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithNullableStyle(style))
+	}
+	if d.Views != "" {
+		mode, err := migu.ParseViewMode(d.Views)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithViewMode(mode))
+	}
+	if d.SkipUnsupported {
+		var skipped []*migu.UnsupportedColumn
+		defer func() {
+			for _, c := range skipped {
+				fmt.Fprintf(os.Stderr, "warning: skipped %v\n", c)
+			}
+		}()
+		opts = append(opts, migu.WithSkipUnsupportedTypes(&skipped))
+	}
+	if d.Singular {
+		opts = append(opts, migu.WithSingularStructNames())
+	}
+	if len(d.TrimPrefixes) > 0 {
+		opts = append(opts, migu.WithTrimTablePrefix(d.TrimPrefixes...))
+	}
+	if d.ColumnConstants {
+		opts = append(opts, migu.WithColumnConstants())
+	}
+	if d.TableNameConstants {
+		opts = append(opts, migu.WithTableNameConstants())
+	}
+	if d.TableNameMethod {
+		opts = append(opts, migu.WithTableNameMethod())
+	}
+	if d.CRUD {
+		opts = append(opts, migu.WithCRUD())
+	}
+	if d.Merge {
+		opts = append(opts, migu.WithMerge())
+	}
+	if d.Template != "" {
+		tmpl, err := template.ParseFiles(d.Template)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithTemplate(tmpl))
+	}
+	if d.Dir != "" {
+		return migu.FprintDir(d.Dir, di, opts...)
+	}
 	out := os.Stdout
 	if filename != "" {
 		file, err := os.Create(filename)
@@ -69,5 +203,5 @@ func (d *dump) run(di dialect.Dialect, filename string) error {
 		defer file.Close()
 		out = file
 	}
-	return migu.Fprint(out, di)
+	return migu.Fprint(out, di, opts...)
 }
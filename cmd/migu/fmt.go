@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/naoina/migu"
+	"github.com/naoina/migu/dialect"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	format := &format{}
+	fmtCmd := &cobra.Command{
+		Use:   "fmt [OPTIONS] [FILE|DIRECTORY]...",
+		Short: "canonicalize the migu tags of the struct files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return format.Execute(args, option)
+		},
+	}
+	fmtCmd.Flags().BoolVarP(&format.Write, "write", "w", false, "Write the result to the file instead of standard output")
+	fmtCmd.Flags().BoolVarP(&format.List, "list", "l", false, "List the files whose formatting differs")
+	fmtCmd.SetUsageTemplate(usageTemplate + "\nWith no FILE, or when FILE is -, read standard input.\n")
+	rootCmd.AddCommand(fmtCmd)
+}
+
+type format struct {
+	Write bool
+	List  bool
+}
+
+func (f *format) Execute(args []string, opt *Option) error {
+	opts := dialectOptions(opt)
+	// The dialect is used only for the column types, so it has no database connection.
+	var di dialect.Dialect
+	switch typ := opt.global.DatabaseType; typ {
+	case databaseTypeMySQL, databaseTypeMariaDB:
+		di = dialect.NewMySQL(nil, opts...)
+	case databaseTypeSpanner:
+		di = dialect.NewSpanner("", opts...)
+	default:
+		return fmt.Errorf("BUG: unknown database type: %s", typ)
+	}
+	if len(args) == 0 || (len(args) == 1 && args[0] == migu.StdinFilename) {
+		formatted, err := migu.FormatFile(di, migu.StdinFilename, nil)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(formatted)
+		return err
+	}
+	for _, arg := range args {
+		files, err := goFiles(arg)
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := f.formatFile(di, file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f *format) formatFile(d dialect.Dialect, file string) error {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	formatted, err := migu.FormatFile(d, file, src)
+	if err != nil {
+		return err
+	}
+	changed := !bytes.Equal(src, formatted)
+	if f.List && changed {
+		fmt.Println(file)
+	}
+	if f.Write {
+		if !changed {
+			return nil
+		}
+		fi, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(file, formatted, fi.Mode().Perm())
+	}
+	if !f.List {
+		_, err = os.Stdout.Write(formatted)
+	}
+	return err
+}
+
+// goFiles returns the Go files of arg except the test files if arg is a directory, or arg itself otherwise.
+func goFiles(arg string) ([]string, error) {
+	fi, err := os.Stat(arg)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{arg}, nil
+	}
+	matches, err := filepath.Glob(filepath.Join(arg, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files := matches[:0]
+	for _, file := range matches {
+		if !strings.HasSuffix(file, "_test.go") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
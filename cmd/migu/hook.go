@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// hookStatements returns the statements of --pre-sql and --pre-sql-file if pre is true, or --post-sql and --post-sql-file otherwise.
+// The statements of the files follow the statements given directly.
+func (s *sync) hookStatements(pre bool) ([]string, error) {
+	sqls, files := s.PostSQLs, s.PostSQLFiles
+	if pre {
+		sqls, files = s.PreSQLs, s.PreSQLFiles
+	}
+	stmts := append([]string(nil), sqls...)
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, splitStatements(string(b))...)
+	}
+	return stmts, nil
+}
+
+// splitStatements splits script into the statements by the semicolons at the end of the lines.
+// The empty statements and the lines that start with "--" are skipped.
+func splitStatements(script string) []string {
+	var stmts []string
+	var lines []string
+	flush := func() {
+		if stmt := strings.TrimSpace(strings.Join(lines, "\n")); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			lines = append(lines, strings.TrimSuffix(strings.TrimRight(line, " \t\r"), ";"))
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	flush()
+	return stmts
+}
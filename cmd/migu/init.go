@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/naoina/migu"
+	"github.com/naoina/migu/dialect"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	initialize := &initialize{}
+	initCmd := &cobra.Command{
+		Use:   "init [OPTIONS] DATABASE DIRECTORY",
+		Short: "write Go code of the existing database schema to start managing it by the structs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return initialize.Execute(args, option)
+		},
+	}
+	initCmd.Flags().StringVar(&initialize.Package, "package", "", "Package name of Go code (default the base name of DIRECTORY)")
+	initCmd.Flags().BoolVar(&initialize.Force, "force", false, "Write Go code even if DIRECTORY already has Go files")
+	initCmd.Flags().StringSliceVar(&initialize.Includes, "include", nil, "Write only the tables that match PATTERN (regular expression, can be specified multiple times)")
+	initCmd.Flags().StringSliceVar(&initialize.Excludes, "exclude", nil, "Skip the tables that match PATTERN (regular expression, can be specified multiple times)")
+	initCmd.Flags().BoolVar(&initialize.Singular, "singular", false, "Singularize the table names for the struct names")
+	initCmd.Flags().StringSliceVar(&initialize.TrimPrefixes, "trim-prefix", nil, "Remove PREFIX from the table names for the struct names (can be specified multiple times)")
+	rootCmd.AddCommand(initCmd)
+}
+
+type initialize struct {
+	Package string
+	Force   bool
+
+	Includes []string
+	Excludes []string
+
+	Singular     bool
+	TrimPrefixes []string
+}
+
+func (i *initialize) Execute(args []string, opt *Option) error {
+	if len(args) < 2 {
+		return fmt.Errorf("too few arguments")
+	}
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments")
+	}
+	dbname, dir := args[0], args[1]
+	if !i.Force {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			return err
+		}
+		if len(files) > 0 {
+			return fmt.Errorf("%s already has Go files. Use --force to write into it anyway", dir)
+		}
+	}
+	di, closeDB, err := openDialect(opt, dbname)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	return i.run(di, dir)
+}
+
+func (i *initialize) run(d dialect.Dialect, dir string) error {
+	// The files are written without the header of the generated code since they are maintained by hand from now on.
+	opts := []migu.Option{migu.WithEditableFiles()}
+	if i.Package != "" {
+		opts = append(opts, migu.WithPackageName(i.Package))
+	}
+	if len(i.Includes) > 0 {
+		opts = append(opts, migu.WithIncludeTables(i.Includes...))
+	}
+	if len(i.Excludes) > 0 {
+		opts = append(opts, migu.WithExcludeTables(i.Excludes...))
+	}
+	if i.Singular {
+		opts = append(opts, migu.WithSingularStructNames())
+	}
+	if len(i.TrimPrefixes) > 0 {
+		opts = append(opts, migu.WithTrimTablePrefix(i.TrimPrefixes...))
+	}
+	if err := migu.FprintDir(dir, d, opts...); err != nil {
+		return err
+	}
+	fmt.Printf("Go code of the schema has been written into %s.\n", dir)
+	fmt.Printf("Edit the structs and run `%s sync DATABASE %s` to change the schema.\n", progName, dir)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/naoina/migu"
+)
+
+// errAborted is returned by selectOperations if the user aborts the migration.
+var errAborted = errors.New("aborted")
+
+const interactiveHelp = `y - apply this operation
+n - skip this operation
+a - apply this operation and the remaining operations of the table
+s - skip this operation and the remaining operations of the table
+q - abort without applying any operations
+? - print help
+`
+
+// selectOperations shows each operation of ops to w and prompts whether to apply it by reading the answers from r.
+// It returns the operations to apply. Nothing is applied until all operations are answered, so that aborting leaves the database as it is.
+func selectOperations(r io.Reader, w io.Writer, ops []migu.Operation) ([]migu.Operation, error) {
+	scanner := bufio.NewScanner(r)
+	tables := map[string]bool{}
+	var selected []migu.Operation
+	for i, op := range ops {
+		if apply, ok := tables[op.Table]; ok {
+			if apply {
+				selected = append(selected, op)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "--------operation %d/%d: %s on %s (%s)--------\n", i+1, len(ops), op.Kind, op.Table, op.Safety)
+		fmt.Fprintf(w, "%s\n", op.SQL)
+	prompt:
+		for {
+			fmt.Fprint(w, "Apply this operation [y,n,a,s,q,?]? ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				return nil, fmt.Errorf("%w: unexpected end of input", errAborted)
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "y", "yes":
+				selected = append(selected, op)
+				break prompt
+			case "n", "no":
+				break prompt
+			case "a":
+				selected = append(selected, op)
+				tables[op.Table] = true
+				break prompt
+			case "s":
+				tables[op.Table] = false
+				break prompt
+			case "q":
+				return nil, errAborted
+			default:
+				fmt.Fprint(w, interactiveHelp)
+			}
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/naoina/migu"
+	"github.com/spf13/cobra"
+)
+
+// errLintFailed is returned by lint command if there are the issues of --fail-on severity or higher.
+var errLintFailed = errors.New("lint failed")
+
+func init() {
+	lint := &lint{}
+	lintCmd := &cobra.Command{
+		Use:   "lint [OPTIONS] [FILE|DIRECTORY]",
+		Short: "find the smells of the schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := lint.Execute(args, option)
+			if errors.Is(err, errLintFailed) {
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+	lintCmd.Flags().StringArrayVar(&lint.Severities, "severity", nil, "Change the severity of RULE=SEVERITY (off, info, warning or error, can be specified multiple times)")
+	lintCmd.Flags().StringVar(&lint.FailOn, "fail-on", "error", "Exit with non-zero status if there are the issues of SEVERITY or higher")
+	lintCmd.Flags().StringVar(&lint.Database, "database", "", "Also lint the schema of DATABASE on the server")
+	lintCmd.Flags().BoolVar(&lint.ListRules, "list-rules", false, "Show the rules with the default severities and exit")
+	lintCmd.SetUsageTemplate(usageTemplate + "\nWith no FILE, or when FILE is -, read standard input unless --database is given.\n")
+	rootCmd.AddCommand(lintCmd)
+}
+
+type lint struct {
+	Severities []string
+	FailOn     string
+	Database   string
+	ListRules  bool
+}
+
+func (l *lint) Execute(args []string, opt *Option) error {
+	if l.ListRules {
+		for _, rule := range migu.LintRules {
+			fmt.Printf("%-24s %-8s %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		return nil
+	}
+	var file string
+	switch len(args) {
+	case 0:
+	case 1:
+		file = args[0]
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+	failOn, err := migu.ParseSeverity(l.FailOn)
+	if err != nil {
+		return err
+	}
+	var opts []migu.Option
+	for _, s := range l.Severities {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid severity: %s", s)
+		}
+		sev, err := migu.ParseSeverity(kv[1])
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithLintSeverity(kv[0], sev))
+	}
+	var issues []*migu.LintIssue
+	if file != "" || l.Database == "" {
+		file, src := source(file)
+		fileIssues, err := migu.Lint(file, src, opts...)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, fileIssues...)
+	}
+	if l.Database != "" {
+		dbIssues, err := l.lintDatabase(opt, opts)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, dbIssues...)
+	}
+	var failed int
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity >= failOn {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d issue(s) of %s or higher", errLintFailed, failed, failOn)
+	}
+	return nil
+}
+
+// lintDatabase lints Go's structs that are generated from the schema of --database.
+// The positions of the issues are the lines of the generated code in the file named after the database.
+func (l *lint) lintDatabase(opt *Option, opts []migu.Option) ([]*migu.LintIssue, error) {
+	di, closeDB, err := openDialect(opt, l.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer closeDB()
+	var buf bytes.Buffer
+	if err := migu.Fprint(&buf, di); err != nil {
+		return nil, err
+	}
+	return migu.Lint(l.Database+".go", buf.Bytes(), opts...)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+
+	"github.com/naoina/migu"
+)
+
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// newLogger returns the logger that writes the logs to the standard error in the format of --log-format.
+// The level is debug if --verbose is given, error if --quiet is given, and info otherwise.
+func newLogger(opt *Option) migu.Logger {
+	level := migu.LogLevelInfo
+	switch {
+	case opt.global.Quiet:
+		level = migu.LogLevelError
+	case opt.global.Verbose:
+		level = migu.LogLevelDebug
+	}
+	if opt.global.LogFormat == logFormatJSON {
+		return migu.NewJSONLogger(os.Stderr, level)
+	}
+	return migu.NewTextLogger(os.Stderr, level)
+}
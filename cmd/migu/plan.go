@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/naoina/migu"
+	"github.com/naoina/migu/dialect"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	plan := &plan{}
+	planCmd := &cobra.Command{
+		Use:   "plan [OPTIONS] DATABASE [FILE|DIRECTORY]",
+		Short: "show the operations to synchronize the database schema as JSON",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return plan.Execute(args, option)
+		},
+	}
+	planCmd.Flags().StringSliceVar(&plan.Includes, "include", nil, "Plan only the tables that match PATTERN (regular expression, can be specified multiple times)")
+	planCmd.Flags().StringSliceVar(&plan.Excludes, "exclude", nil, "Skip the tables that match PATTERN (regular expression, can be specified multiple times)")
+	planCmd.Flags().StringVar(&plan.SQLFile, "sql", "", "Write the plan to FILE as SQL with the header comment of the time, the git revision of the source and the schema fingerprint instead of JSON")
+	planCmd.SetUsageTemplate(usageTemplate + "\nWith no FILE, or when FILE is -, read standard input.\n")
+	rootCmd.AddCommand(planCmd)
+}
+
+type plan struct {
+	Includes []string
+	Excludes []string
+	SQLFile  string
+
+	logger migu.Logger
+}
+
+// planOutput is the JSON output of plan command.
+type planOutput struct {
+	Operations []*planOperation `json:"operations"`
+}
+
+type planOperation struct {
+	Kind        string   `json:"kind"`
+	Table       string   `json:"table"`
+	Column      string   `json:"column,omitempty"`
+	SQL         string   `json:"sql"`
+	Safety      string   `json:"safety"`
+	Destructive bool     `json:"destructive"`
+	Down        []string `json:"down,omitempty"`
+}
+
+func (p *plan) Execute(args []string, opt *Option) error {
+	var dbname string
+	var file string
+	switch len(args) {
+	case 0:
+		return fmt.Errorf("too few arguments")
+	case 1:
+		dbname = args[0]
+	case 2:
+		dbname, file = args[0], args[1]
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+	di, closeDB, err := openDialect(opt, dbname)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	p.logger = newLogger(opt)
+	file, src := source(file)
+	return p.run(di, file, src)
+}
+
+func (p *plan) run(d dialect.Dialect, file string, src interface{}) error {
+	opts := []migu.Option{migu.WithLogger(p.logger)}
+	if len(p.Includes) > 0 {
+		opts = append(opts, migu.WithIncludeTables(p.Includes...))
+	}
+	if len(p.Excludes) > 0 {
+		opts = append(opts, migu.WithExcludeTables(p.Excludes...))
+	}
+	plan, err := migu.Plan(d, file, src, opts...)
+	if err != nil {
+		return err
+	}
+	if p.SQLFile != "" {
+		return p.writeSQLFile(plan, file)
+	}
+	output := &planOutput{
+		Operations: []*planOperation{},
+	}
+	for _, op := range plan.Operations {
+		output.Operations = append(output.Operations, &planOperation{
+			Kind:        op.Kind.String(),
+			Table:       op.Table,
+			Column:      op.Column,
+			SQL:         op.SQL,
+			Safety:      op.Safety.String(),
+			Destructive: op.IsDestructive(),
+			Down:        op.Down,
+		})
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(output)
+}
+
+// writeSQLFile writes plan to the file of --sql with the provenance of the source file.
+func (p *plan) writeSQLFile(plan *migu.MigrationPlan, file string) error {
+	metadata := migu.SQLFileMetadata{
+		Source: file,
+	}
+	if file == migu.StdinFilename {
+		metadata.Source = "<standard input>"
+	} else {
+		metadata.Revision = gitRevision(file)
+	}
+	f, err := os.Create(p.SQLFile)
+	if err != nil {
+		return err
+	}
+	if err := plan.WriteSQL(f, metadata); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// gitRevision returns the commit hash of HEAD of the git repository that has file.
+// "-dirty" is appended if file has the uncommitted changes. It returns the empty string if file is not in a git repository.
+func gitRevision(file string) string {
+	dir, base := file, "."
+	if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
+		dir, base = filepath.Split(file)
+		if dir == "" {
+			dir = "."
+		}
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	revision := strings.TrimSpace(string(out))
+	if out, err := exec.Command("git", "-C", dir, "status", "--porcelain", "--", base).Output(); err == nil && len(bytes.TrimSpace(out)) > 0 {
+		revision += "-dirty"
+	}
+	return revision
+}
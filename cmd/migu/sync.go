@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path"
+	"strconv"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/naoina/migu"
 	"github.com/naoina/migu/dialect"
 	"github.com/spf13/cobra"
@@ -13,6 +18,9 @@ import (
 
 var (
 	dryRunMarker = "dry-run "
+
+	// errPendingChanges is returned by sync command in --check mode if there are the pending statements.
+	errPendingChanges = errors.New("the database schema is not synchronized")
 )
 
 func init() {
@@ -21,21 +29,97 @@ func init() {
 		Use:   "sync [OPTIONS] DATABASE [FILE|DIRECTORY]",
 		Short: "synchronize the database schema",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return sync.Execute(args, option)
+			err := sync.Execute(args, option)
+			if errors.Is(err, errPendingChanges) {
+				cmd.SilenceErrors, cmd.SilenceUsage = true, true
+			}
+			return err
 		},
 	}
 	syncCmd.Flags().BoolVar(&sync.DryRun, "dry-run", false, "")
-	syncCmd.Flags().BoolVarP(&sync.Quiet, "quiet", "q", false, "")
+	syncCmd.Flags().BoolVar(&sync.Check, "check", false, "Show the pending statements without applying them, and exit with status 2 if any (1 on errors, 0 if synchronized)")
+	syncCmd.Flags().BoolVar(&sync.Diff, "diff", false, "Show the pending statements grouped by table in the style of unified diff without applying them (requires --dry-run or --check)")
+	syncCmd.Flags().BoolVarP(&sync.Interactive, "interactive", "i", false, "Prompt whether to apply each operation or the remaining operations of each table. Nothing is applied until all operations are answered (requires FILE)")
+	syncCmd.Flags().BoolVar(&sync.Watch, "watch", false, "Run again every time FILE or the Go files in DIRECTORY are saved until interrupted. Use with --dry-run to show only the diff")
+	syncCmd.Flags().DurationVar(&sync.WatchInterval, "watch-interval", time.Second, "Check the files of --watch every DURATION")
+	syncCmd.Flags().StringVar(&sync.Color, "color", "auto", "Colorize the output of --diff in MODE (auto, always or never)")
+	syncCmd.Flags().StringSliceVar(&sync.Includes, "include", nil, "Synchronize only the tables that match PATTERN (regular expression, can be specified multiple times)")
+	syncCmd.Flags().StringSliceVar(&sync.Excludes, "exclude", nil, "Skip the tables that match PATTERN (regular expression, can be specified multiple times)")
+	syncCmd.Flags().StringArrayVar(&sync.Targets, "target", nil, "Synchronize the database of DSN instead of DATABASE. DATABASE argument must be omitted (MySQL/MariaDB only, can be specified multiple times)")
+	syncCmd.Flags().BoolVar(&sync.ContinueOnError, "continue-on-error", false, "Continue to synchronize the remaining targets after a target fails")
+	syncCmd.Flags().BoolVar(&sync.AllowDrop, "allow-drop", false, "Apply the destructive and the data-lossy operations such as DROP TABLE, DROP COLUMN and narrowing the column type")
+	syncCmd.Flags().BoolVar(&sync.AllowDestructive, "allow-destructive", false, "Apply the destructive operations such as DROP TABLE and DROP COLUMN")
+	syncCmd.Flags().BoolVar(&sync.AllowDataLossy, "allow-data-lossy", false, "Apply the data-lossy operations such as narrowing the column type")
+	syncCmd.Flags().BoolVar(&sync.PruneSeeds, "prune-seeds", false, "Delete the rows of the seed tables that are not declared in the seed data")
+	syncCmd.Flags().StringSliceVar(&sync.DisallowedSafety, "disallow-safety", nil, "Abort if the migration has the operations of CLASS (locking, destructive or data-lossy, can be specified multiple times)")
+	syncCmd.Flags().IntVar(&sync.Retry, "retry", 0, "Retry a statement up to N times when it fails with a lock wait timeout or a deadlock")
+	syncCmd.Flags().DurationVar(&sync.RetryBackoff, "retry-backoff", time.Second, "Wait DURATION before the first retry of --retry. The wait is doubled on each retry")
+	syncCmd.Flags().BoolVar(&sync.History, "history", false, "Record the applied statements in the history table ("+migu.DefaultHistoryTable+"), and skip the statements of the same plan that have already been applied")
+	syncCmd.Flags().StringArrayVar(&sync.PreSQLs, "pre-sql", nil, "Execute SQL before the migration on the same connection (can be specified multiple times)")
+	syncCmd.Flags().StringArrayVar(&sync.PreSQLFiles, "pre-sql-file", nil, "Execute the statements in FILE separated by semicolons before the migration on the same connection (can be specified multiple times)")
+	syncCmd.Flags().StringArrayVar(&sync.PostSQLs, "post-sql", nil, "Execute SQL after the migration on the same connection (can be specified multiple times)")
+	syncCmd.Flags().StringArrayVar(&sync.PostSQLFiles, "post-sql-file", nil, "Execute the statements in FILE separated by semicolons after the migration on the same connection (can be specified multiple times)")
 	syncCmd.SetUsageTemplate(usageTemplate + "\nWith no FILE, or when FILE is -, read standard input.\n")
 	rootCmd.AddCommand(syncCmd)
 }
 
 type sync struct {
 	DryRun bool
-	Quiet  bool
+	Check  bool
+	Diff   bool
+	Color  string
+
+	Interactive bool
+
+	Watch         bool
+	WatchInterval time.Duration
+
+	AllowDrop        bool
+	AllowDestructive bool
+	AllowDataLossy   bool
+	DisallowedSafety []string
+
+	PruneSeeds bool
+
+	Retry        int
+	RetryBackoff time.Duration
+	History      bool
+
+	Includes []string
+	Excludes []string
+
+	Targets         []string
+	ContinueOnError bool
+
+	PreSQLs      []string
+	PreSQLFiles  []string
+	PostSQLs     []string
+	PostSQLFiles []string
+
+	quiet   bool
+	jsonLog bool
+	logger  migu.Logger
 }
 
 func (s *sync) Execute(args []string, opt *Option) error {
+	if s.Diff && !s.DryRun && !s.Check {
+		return fmt.Errorf("--diff requires --dry-run or --check")
+	}
+	if !s.DryRun {
+		dryRunMarker = ""
+	}
+	s.quiet = opt.global.Quiet
+	s.jsonLog = opt.global.LogFormat == logFormatJSON
+	s.logger = newLogger(opt)
+	if s.Interactive && (s.DryRun || s.Check || s.Watch || len(s.Targets) > 0) {
+		return fmt.Errorf("--interactive cannot be used with --dry-run, --check, --watch or --target")
+	}
+	if len(s.Targets) > 0 {
+		if s.Watch {
+			return fmt.Errorf("--watch cannot be used with --target")
+		}
+		return s.executeTargets(args, opt)
+	}
 	var dbname string
 	var file string
 	switch len(args) {
@@ -48,70 +132,358 @@ func (s *sync) Execute(args []string, opt *Option) error {
 	default:
 		return fmt.Errorf("too many arguments")
 	}
-	var opts []dialect.Option
-	if columnTypes := opt.global.ColumnTypes; len(columnTypes) != 0 {
-		opts = append(opts, dialect.WithColumnType(columnTypes))
+	di, closeDB, err := openDialect(opt, dbname)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	if s.Watch {
+		return s.watch(di, file)
+	}
+	file, src := source(file)
+	if s.Interactive && file == migu.StdinFilename {
+		// The standard input is used to answer the prompts.
+		return fmt.Errorf("--interactive requires FILE or DIRECTORY")
+	}
+	return s.run(di, file, src)
+}
+
+// executeTargets synchronizes the databases of --target in order, and reports the result of each target.
+func (s *sync) executeTargets(args []string, opt *Option) error {
+	var file string
+	switch len(args) {
+	case 0:
+	case 1:
+		file = args[0]
+	default:
+		return fmt.Errorf("too many arguments")
 	}
-	var di dialect.Dialect
 	switch typ := opt.global.DatabaseType; typ {
 	case databaseTypeMySQL, databaseTypeMariaDB:
-		db, err := openDatabase(dbname)
+		// do nothing.
+	default:
+		return fmt.Errorf("--target is not supported for %s", typ)
+	}
+	file, src := source(file)
+	if file == migu.StdinFilename {
+		// The standard input is read once and used for all targets.
+		b, err := io.ReadAll(os.Stdin)
 		if err != nil {
 			return err
 		}
-		defer db.Close()
-		di = dialect.NewMySQL(db, opts...)
-	case databaseTypeSpanner:
-		di = dialect.NewSpanner(path.Join("projects", opt.spanner.Project, "instances", opt.spanner.Instance, "databases", dbname), opts...)
-	default:
-		return fmt.Errorf("BUG: unknown database type: %s", typ)
+		src = b
 	}
-	if !s.DryRun {
-		dryRunMarker = ""
+	opts := dialectOptions(opt)
+	var failed, pending int
+	for i, target := range s.Targets {
+		dsn, err := dialect.MySQLDSN(target)
+		if err != nil {
+			return fmt.Errorf("invalid target #%d: %w", i+1, err)
+		}
+		name := targetName(dsn)
+		s.printf("========%starget %s========\n", dryRunMarker, name)
+		err = s.runTarget(dsn, file, src, opts)
+		if errors.Is(err, errPendingChanges) {
+			pending++
+			s.printf("%s: not synchronized\n", name)
+			s.log(migu.LogLevelInfo, "the target is not synchronized", migu.Attribute{Key: "target", Value: name})
+			continue
+		}
+		if err != nil {
+			failed++
+			if s.jsonLog {
+				s.log(migu.LogLevelError, "failed to synchronize the target", migu.Attribute{Key: "target", Value: name}, migu.Attribute{Key: migu.AttributeError, Value: err.Error()})
+			} else {
+				fmt.Fprintf(os.Stderr, "%s: failed: %v\n", name, err)
+			}
+			if !s.ContinueOnError {
+				for _, target := range s.Targets[i+1:] {
+					dsn, _ := dialect.MySQLDSN(target)
+					if s.jsonLog {
+						s.log(migu.LogLevelWarn, "the target is not attempted", migu.Attribute{Key: "target", Value: targetName(dsn)})
+					} else {
+						fmt.Fprintf(os.Stderr, "%s: not attempted\n", targetName(dsn))
+					}
+				}
+				break
+			}
+			continue
+		}
+		s.printf("%s: ok\n", name)
+		s.log(migu.LogLevelInfo, "synchronized the target", migu.Attribute{Key: "target", Value: name})
 	}
-	return s.run(di, file)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(s.Targets))
+	}
+	if pending > 0 {
+		return errPendingChanges
+	}
+	return nil
 }
 
-func (s *sync) run(d dialect.Dialect, file string) error {
-	var src interface{}
-	switch file {
-	case "", "-":
-		file = ""
-		src = os.Stdin
+func (s *sync) runTarget(dsn, file string, src interface{}, opts []dialect.Option) error {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return err
 	}
-	sqls, err := migu.Diff(d, file, src)
+	if err := configureConnection(config); err != nil {
+		return err
+	}
+	db, err := sql.Open("mysql", config.FormatDSN())
 	if err != nil {
 		return err
 	}
-	var tx dialect.Transactioner
-	if !s.DryRun {
-		if tx, err = d.Begin(); err != nil {
+	defer db.Close()
+	return s.run(dialect.NewMySQL(db, opts...), file, src)
+}
+
+// targetName returns the address and the database name of dsn without the credentials.
+func targetName(dsn string) string {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return dsn
+	}
+	return config.Addr + "/" + config.DBName
+}
+
+// source returns the filename and the source of FILE argument.
+// The filename is migu.StdinFilename if file is empty, so that the library reads the standard input.
+func source(file string) (string, interface{}) {
+	if file == "" {
+		return migu.StdinFilename, nil
+	}
+	return file, nil
+}
+
+func (s *sync) run(d dialect.Dialect, file string, src interface{}) error {
+	opts := []migu.Option{migu.WithLogger(&applyLogger{s: s})}
+	if s.Retry > 0 {
+		opts = append(opts, migu.WithRetry(s.Retry, s.RetryBackoff))
+	}
+	if s.History {
+		opts = append(opts, migu.WithHistory())
+	}
+	if len(s.Includes) > 0 {
+		opts = append(opts, migu.WithIncludeTables(s.Includes...))
+	}
+	if len(s.Excludes) > 0 {
+		opts = append(opts, migu.WithExcludeTables(s.Excludes...))
+	}
+	for _, class := range s.DisallowedSafety {
+		safety, err := migu.ParseSafety(class)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, migu.WithDisallowedSafety(safety))
+	}
+	refused := s.refusedSafety()
+	for safety := range refused {
+		opts = append(opts, migu.WithDisallowedSafety(safety))
+	}
+	if s.PruneSeeds {
+		opts = append(opts, migu.WithSeedPrune())
+	}
+	plan, err := migu.Plan(d, file, src, opts...)
+	var safetyErr *migu.SafetyError
+	if errors.As(err, &safetyErr) {
+		for _, op := range safetyErr.Operations {
+			if _, ok := refused[op.Safety]; ok {
+				return fmt.Errorf("%w\nUse --dry-run to review them, and --allow-drop (or --allow-destructive and --allow-data-lossy) to apply them", err)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	// The plan has the upserts of the seed data after the schema changes.
+	ops := plan.Operations
+	if s.Diff {
+		color, err := useColor(s.Color, os.Stdout)
+		if err != nil {
 			return err
 		}
+		if !s.quiet {
+			printDiff(os.Stdout, ops, color)
+		}
 	}
-	for _, sql := range sqls {
-		s.printf("--------%sapplying--------\n", dryRunMarker)
-		s.printf("%s\n", sql)
-		start := time.Now()
-		if !s.DryRun {
-			if err := tx.Exec(sql); err != nil {
-				tx.Rollback()
-				return err
+	if s.Check {
+		if len(ops) == 0 {
+			return nil
+		}
+		if !s.Diff {
+			for _, op := range ops {
+				s.printf("%s;\n", op.SQL)
+				s.log(migu.LogLevelInfo, "pending statement", operationAttributes(op)...)
 			}
 		}
-		d := time.Since(start)
-		s.printf("--------%sdone %.3fs--------\n", dryRunMarker, d.Seconds()/time.Second.Seconds())
+		return errPendingChanges
+	}
+	if s.Diff {
+		return nil
+	}
+	if s.Interactive && len(ops) > 0 {
+		if ops, err = selectOperations(os.Stdin, os.Stdout, ops); err != nil {
+			return err
+		}
+		plan.Operations = ops
+	}
+	// The hooks are not executed if there is nothing to migrate.
+	if len(ops) == 0 {
+		return nil
+	}
+	pre, err := s.hookStatements(true)
+	if err != nil {
+		return err
+	}
+	post, err := s.hookStatements(false)
+	if err != nil {
+		return err
 	}
 	if s.DryRun {
+		for _, sql := range pre {
+			s.printStatement("pre-sql", sql, 0, []migu.Attribute{{Key: migu.AttributeStatement, Value: sql}})
+		}
+		for _, op := range ops {
+			s.printStatement("applying", op.SQL, 0, operationAttributes(op))
+		}
+		for _, sql := range post {
+			s.printStatement("post-sql", sql, 0, []migu.Attribute{{Key: migu.AttributeStatement, Value: sql}})
+		}
 		return nil
-	} else {
-		return tx.Commit()
 	}
+	// The hooks are executed on the same session as the statements of the plan, since Apply uses the pinned session as it is.
+	ctx := context.Background()
+	d, release, err := pinSession(ctx, d)
+	if err != nil {
+		return err
+	}
+	defer release()
+	for _, sql := range pre {
+		if err := s.execHook(d, "pre-sql", sql); err != nil {
+			return fmt.Errorf("pre-sql: %w", err)
+		}
+	}
+	if _, err := plan.Apply(ctx, d); err != nil {
+		return err
+	}
+	for _, sql := range post {
+		if err := s.execHook(d, "post-sql", sql); err != nil {
+			return fmt.Errorf("post-sql: %w", err)
+		}
+	}
+	return nil
+}
+
+// pinSession returns the dialect that executes all statements on a single connection if d supports it.
+func pinSession(ctx context.Context, d dialect.Dialect) (dialect.Dialect, func() error, error) {
+	noop := func() error { return nil }
+	p, ok := d.(dialect.SessionPinner)
+	if !ok {
+		return d, noop, nil
+	}
+	pinned, release, err := p.Pin(ctx)
+	if errors.Is(err, dialect.ErrNoSingleConn) {
+		return d, noop, nil
+	}
+	return pinned, release, err
+}
+
+// execHook executes sql of --pre-sql or --post-sql in its own transaction.
+func (s *sync) execHook(d dialect.Dialect, label, sql string) error {
+	start := time.Now()
+	tx, err := d.Begin()
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec(sql); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.printStatement(label, sql, time.Since(start), []migu.Attribute{{Key: migu.AttributeStatement, Value: sql}})
+	return nil
 }
 
+// printStatement prints sql that is executed in duration, or logs it with attrs if --log-format=json is given.
+func (s *sync) printStatement(label, sql string, d time.Duration, attrs []migu.Attribute) {
+	s.printBlock(label, sql, d)
+	attrs = append(attrs, migu.Attribute{Key: migu.AttributeDuration, Value: d.String()}, migu.Attribute{Key: "dry_run", Value: strconv.FormatBool(s.DryRun)})
+	msg := "executed the statement"
+	if label != "applying" {
+		msg = "executed the " + label + " statement"
+	}
+	s.log(migu.LogLevelInfo, msg, attrs...)
+}
+
+// applyLogger is migu.Logger that prints the statements executed by Apply in the same form as the hooks,
+// and passes the other logs to the logger of --log-format.
+type applyLogger struct {
+	s *sync
+}
+
+func (l *applyLogger) Log(level migu.LogLevel, msg string, attrs ...migu.Attribute) {
+	if msg != "executed the statement" || l.s.jsonLog {
+		l.s.logger.Log(level, msg, attrs...)
+		return
+	}
+	var sql string
+	var d time.Duration
+	for _, attr := range attrs {
+		switch attr.Key {
+		case migu.AttributeStatement:
+			sql = attr.Value
+		case migu.AttributeDuration:
+			d, _ = time.ParseDuration(attr.Value)
+		}
+	}
+	l.s.printBlock("applying", sql, d)
+}
+
+// printBlock prints sql between the lines of label and duration.
+func (s *sync) printBlock(label, sql string, d time.Duration) {
+	s.printf("--------%s%s--------\n", dryRunMarker, label)
+	s.printf("%s\n", sql)
+	s.printf("--------%sdone %.3fs--------\n", dryRunMarker, d.Seconds())
+}
+
+// refusedSafety returns the safety classes of the operations that are refused unless they are explicitly allowed by the flags.
+// Nothing is refused in --dry-run and --check mode since the operations are not applied.
+func (s *sync) refusedSafety() map[migu.Safety]struct{} {
+	refused := map[migu.Safety]struct{}{}
+	if s.DryRun || s.Check || s.AllowDrop {
+		return refused
+	}
+	if !s.AllowDestructive {
+		refused[migu.SafetyDestructive] = struct{}{}
+	}
+	if !s.AllowDataLossy {
+		refused[migu.SafetyDataLossy] = struct{}{}
+	}
+	return refused
+}
+
+// printf prints the human-readable output unless --quiet or --log-format=json is given.
 func (s *sync) printf(format string, a ...interface{}) (int, error) {
-	if s.Quiet {
+	if s.quiet || s.jsonLog {
 		return 0, nil
 	}
 	return fmt.Printf(format, a...)
 }
+
+// log logs msg with attrs if --log-format=json is given. Otherwise, the same information is printed by printf.
+func (s *sync) log(level migu.LogLevel, msg string, attrs ...migu.Attribute) {
+	if s.jsonLog {
+		s.logger.Log(level, msg, attrs...)
+	}
+}
+
+// operationAttributes returns the attributes of the logs of op.
+func operationAttributes(op migu.Operation) []migu.Attribute {
+	return []migu.Attribute{
+		{Key: migu.AttributeOperation, Value: op.Kind.String()},
+		{Key: migu.AttributeTable, Value: op.Table},
+		{Key: migu.AttributeStatement, Value: op.SQL},
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// watch runs s.run every time the files of file are saved until interrupted.
+// The files are polled by the interval of --watch-interval. If file is a directory, the "*.go" files in it are watched.
+func (s *sync) watch(d dialect.Dialect, file string) error {
+	if file == "" || file == "-" {
+		return fmt.Errorf("--watch requires FILE or DIRECTORY")
+	}
+	if s.WatchInterval <= 0 {
+		return fmt.Errorf("--watch-interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(s.WatchInterval)
+	defer ticker.Stop()
+	var last string
+	for {
+		state, err := fileState(file)
+		if err != nil {
+			return err
+		}
+		if state != last {
+			last = state
+			fmt.Fprintf(os.Stderr, "========%s %s========\n", time.Now().Format("15:04:05"), file)
+			if err := s.run(d, file, nil); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fileState returns the string that changes when any of the files of file is modified, added or removed.
+func fileState(file string) (string, error) {
+	files := []string{file}
+	if fi, err := os.Stat(file); err != nil {
+		return "", err
+	} else if fi.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(file, "*.go")); err != nil {
+			return "", err
+		}
+		sort.Strings(files)
+	}
+	states := make([]string, 0, len(files))
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return "", err
+		}
+		states = append(states, fmt.Sprintf("%s %d %d", f, fi.Size(), fi.ModTime().UnixNano()))
+	}
+	return strings.Join(states, "\n"), nil
+}
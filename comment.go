@@ -0,0 +1,40 @@
+package migu
+
+import "fmt"
+
+// CommentMode represents how Diff treats the columns that differ only in the comments.
+type CommentMode int
+
+const (
+	// CommentModify modifies the column in the same way as the other differences.
+	CommentModify CommentMode = iota
+
+	// CommentIgnore ignores the differences of the comments unless the column has the other differences.
+	CommentIgnore
+
+	// CommentSeparate modifies the column by the operation of OperationModifyComment kind,
+	// so that the comment changes can be distinguished from the changes of the column definitions.
+	CommentSeparate
+)
+
+func (m CommentMode) String() string {
+	switch m {
+	case CommentModify:
+		return "modify"
+	case CommentIgnore:
+		return "ignore"
+	case CommentSeparate:
+		return "separate"
+	}
+	return fmt.Sprintf("CommentMode(%d)", int(m))
+}
+
+// IsCommentOnlyDifferent reports whether f and another differ only in the comments.
+func (f *field) IsCommentOnlyDifferent(another *field) bool {
+	if f == nil || another == nil || f.Comment == another.Comment {
+		return false
+	}
+	commented := *another
+	commented.Comment = f.Comment
+	return !f.IsDifferent(&commented)
+}
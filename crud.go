@@ -0,0 +1,170 @@
+package migu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// crudImports is the packages that are needed by the CRUD helpers.
+var crudImports = []string{"context", "database/sql"}
+
+var crudTemplate = template.Must(template.New("crud").Parse(`
+// Insert inserts {{.Receiver}} into {{.Table}} table.
+func ({{.Receiver}} *{{.Struct}}) Insert(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}) error {
+	{{if .AutoIncrement}}result, err{{else}}_, err{{end}} := db.ExecContext(ctx, {{printf "%q" .InsertSQL}}{{range .InsertFields}}, {{$.Receiver}}.{{.Name}}{{end}})
+	if err != nil {
+		return err
+	}
+{{- with .AutoIncrement}}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	{{$.Receiver}}.{{.Name}} = {{.Type}}(id)
+{{- end}}
+	return nil
+}
+{{if .PrimaryKeys}}
+// Select{{.Struct}}ByPK returns the row of {{.Table}} table that has the primary key.
+func Select{{.Struct}}ByPK(ctx context.Context, db interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}{{range .PrimaryKeys}}, {{.Param}} {{.Type}}{{end}}) (*{{.Struct}}, error) {
+	var {{.Receiver}} {{.Struct}}
+	if err := db.QueryRowContext(ctx, {{printf "%q" .SelectSQL}}{{range .PrimaryKeys}}, {{.Param}}{{end}}).Scan({{range $i, $f := .Fields}}{{if $i}}, {{end}}&{{$.Receiver}}.{{$f.Name}}{{end}}); err != nil {
+		return nil, err
+	}
+	return &{{.Receiver}}, nil
+}
+{{if .UpdateFields}}
+// Update updates the row of {{.Table}} table that has the primary key of {{.Receiver}}.
+func ({{.Receiver}} *{{.Struct}}) Update(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}) error {
+	_, err := db.ExecContext(ctx, {{printf "%q" .UpdateSQL}}{{range .UpdateFields}}, {{$.Receiver}}.{{.Name}}{{end}}{{range .PrimaryKeys}}, {{$.Receiver}}.{{.Name}}{{end}})
+	return err
+}
+{{end}}
+// Delete deletes the row of {{.Table}} table that has the primary key of {{.Receiver}}.
+func ({{.Receiver}} *{{.Struct}}) Delete(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}) error {
+	_, err := db.ExecContext(ctx, {{printf "%q" .DeleteSQL}}{{range .PrimaryKeys}}, {{$.Receiver}}.{{.Name}}{{end}})
+	return err
+}
+{{end}}`))
+
+type crudField struct {
+	Name   string
+	Type   string
+	Column string
+	Param  string
+}
+
+type crudData struct {
+	Table         string
+	Struct        string
+	Receiver      string
+	Fields        []*crudField
+	InsertFields  []*crudField
+	UpdateFields  []*crudField
+	PrimaryKeys   []*crudField
+	AutoIncrement *crudField
+	InsertSQL     string
+	SelectSQL     string
+	UpdateSQL     string
+	DeleteSQL     string
+}
+
+// fprintCRUD writes the CRUD helpers of Go's struct for the table that use database/sql.
+func fprintCRUD(output io.Writer, d dialect.Dialect, name string, schemas []dialect.ColumnSchema, o *option) error {
+	decl, err := makeStructAST(d, name, schemas, o)
+	if err != nil {
+		return err
+	}
+	spec := decl.(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	data := &crudData{
+		Table:    name,
+		Struct:   spec.Name.Name,
+		Receiver: string(unicode.ToLower([]rune(spec.Name.Name)[0])),
+	}
+	var columns, insertColumns, setColumns, pkColumns []string
+	for i, f := range spec.Type.(*ast.StructType).Fields.List {
+		var typ bytes.Buffer
+		if err := format.Node(&typ, token.NewFileSet(), f.Type); err != nil {
+			return err
+		}
+		schema := schemas[i]
+		field := &crudField{
+			Name:   f.Names[0].Name,
+			Type:   typ.String(),
+			Column: schema.ColumnName(),
+			Param:  toLowerCamelCase(schema.ColumnName()),
+		}
+		if token.IsKeyword(field.Param) || field.Param == "ctx" || field.Param == "db" || field.Param == data.Receiver {
+			field.Param += "_"
+		}
+		data.Fields = append(data.Fields, field)
+		quoted := d.Quote(field.Column)
+		columns = append(columns, quoted)
+		switch {
+		case schema.IsPrimaryKey():
+			data.PrimaryKeys = append(data.PrimaryKeys, field)
+			pkColumns = append(pkColumns, quoted)
+		default:
+			data.UpdateFields = append(data.UpdateFields, field)
+			setColumns = append(setColumns, quoted)
+		}
+		if schema.IsAutoIncrement() && !strings.HasPrefix(field.Type, "*") {
+			data.AutoIncrement = field
+			continue
+		}
+		data.InsertFields = append(data.InsertFields, field)
+		insertColumns = append(insertColumns, quoted)
+	}
+	table := quoteTable(d, name)
+	placeholders := make([]string, len(insertColumns))
+	for i := range insertColumns {
+		placeholders[i] = placeholder(d, i+1)
+	}
+	data.InsertSQL = "INSERT INTO " + table + " (" + strings.Join(insertColumns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	where := crudConditions(d, pkColumns, 1)
+	data.SelectSQL = "SELECT " + strings.Join(columns, ", ") + " FROM " + table + " WHERE " + where
+	data.UpdateSQL = "UPDATE " + table + " SET " + strings.Join(crudAssignments(d, setColumns, 1), ", ") + " WHERE " + crudConditions(d, pkColumns, len(setColumns)+1)
+	data.DeleteSQL = "DELETE FROM " + table + " WHERE " + where
+	var buf bytes.Buffer
+	buf.WriteString("package p\n")
+	if err := crudTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	src = bytes.TrimPrefix(src, []byte("package p\n\n"))
+	_, err = output.Write(append(src, '\n'))
+	return err
+}
+
+// crudAssignments returns "<column> = <placeholder>" of the columns whose placeholders are numbered from start.
+func crudAssignments(d dialect.Dialect, columns []string, start int) []string {
+	exprs := make([]string, len(columns))
+	for i, column := range columns {
+		exprs[i] = column + " = " + placeholder(d, start+i)
+	}
+	return exprs
+}
+
+// crudConditions returns the condition that all columns are equal to the placeholders that are numbered from start.
+func crudConditions(d dialect.Dialect, columns []string, start int) string {
+	return strings.Join(crudAssignments(d, columns, start), " AND ")
+}
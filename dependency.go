@@ -0,0 +1,57 @@
+package migu
+
+import "strings"
+
+// sortTablesByDependency returns names in the order that the tables referred by the foreign keys come first.
+// The order of names is kept as much as possible, and the tables in a reference cycle are left in the order of names.
+func sortTablesByDependency(names []string, tableMap map[string]*table) []string {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	deps := make([][]int, len(names))
+	for i, name := range names {
+		tbl := tableMap[name]
+		if tbl == nil {
+			continue
+		}
+		for _, f := range tbl.Fields {
+			if !strings.Contains(f.ForeignKey, ".") {
+				continue
+			}
+			parent, _ := splitReference(f.ForeignKey)
+			if j, ok := index[parent]; ok && j != i {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	sorted := make([]string, 0, len(names))
+	done := make([]bool, len(names))
+	for len(sorted) < len(names) {
+		next := -1
+		for i := range names {
+			if done[i] {
+				continue
+			}
+			if next < 0 {
+				next = i // the first remaining table is used if all remaining tables are in cycles.
+			}
+			if ready(deps[i], done) {
+				next = i
+				break
+			}
+		}
+		done[next] = true
+		sorted = append(sorted, names[next])
+	}
+	return sorted
+}
+
+func ready(deps []int, done []bool) bool {
+	for _, j := range deps {
+		if !done[j] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,5 +1,23 @@
 package dialect
 
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DB is the minimal interface of the database connection that is used by the dialects that use database/sql.
+// *sql.DB and *sql.Conn implement it, so the connection can be wrapped to record or rewrite the queries.
+// The results are the types of database/sql that cannot be made without a driver, so a fake of the database must be
+// given as *sql.DB of the driver such as sqlmock rather than an in-memory implementation of DB.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
 type Dialect interface {
 	ColumnSchema(tables ...string) ([]ColumnSchema, error)
 	ColumnType(name string) string
@@ -10,6 +28,7 @@ type Dialect interface {
 	QuoteString(s string) string
 
 	CreateTableSQL(table Table) []string
+	DropTableSQL(table Table) []string
 	AddColumnSQL(field Field) []string
 	DropColumnSQL(field Field) []string
 	ModifyColumnSQL(oldField, newfield Field) []string
@@ -33,23 +52,230 @@ type ColumnSchema interface {
 	Comment() (string, bool)
 }
 
+// MultiIndexColumnSchema is implemented by the ColumnSchema that can report all indexes that contain the column.
+// The order of the indexes is deterministic. Index of the ColumnSchema returns the first one.
+type MultiIndexColumnSchema interface {
+	Indexes() []ColumnIndex
+}
+
+// ColumnIndex is the index that contains the column.
+type ColumnIndex struct {
+	Name   string
+	Unique bool
+
+	// Position is the position of the column in the index starting at 1. It is 0 if unknown.
+	Position int
+}
+
+// ForeignKeyColumnSchema is implemented by the ColumnSchema that can report the column that the column refers to.
+type ForeignKeyColumnSchema interface {
+	// ForeignKey returns the referenced column as "<table>.<column>".
+	ForeignKey() (string, bool)
+}
+
 type Transactioner interface {
 	Exec(sql string, args ...interface{}) error
 	Commit() error
 	Rollback() error
 }
 
+// RowsAffectedExecer is implemented by the Transactioner that can report the number of rows affected by a statement.
+type RowsAffectedExecer interface {
+	ExecRowsAffected(sql string, args ...interface{}) (int64, error)
+}
+
+// Retryable is the interface that reports whether a failed statement can be retried.
+type Retryable interface {
+	// IsRetryable returns true if err is a transient error such as a lock wait timeout or a deadlock.
+	IsRetryable(err error) bool
+}
+
 type PrimaryKeyModifier interface {
 	ModifyPrimaryKeySQL(oldPrimaryKeys, newPrimaryKeys []Field) []string
 }
 
+// RowCounter is implemented by the dialect that can count the rows on the database.
+type RowCounter interface {
+	// CountRows executes query that returns a single integer such as "SELECT COUNT(*) FROM ..." and returns the result.
+	CountRows(query string) (int64, error)
+}
+
+// Seeder is implemented by the dialect that can upsert the seed rows.
+// The values and the keys are given as SQL literals.
+type Seeder interface {
+	// UpsertSQL returns the SQLs to insert the row, or to update it if the row that has the same primary key exists.
+	UpsertSQL(table string, columns, values, primaryKeys []string) []string
+
+	// PruneSQL returns the SQLs to delete the rows whose primary key is not in keys.
+	PruneSQL(table string, primaryKeys []string, keys [][]string) []string
+}
+
+// TableQuoter is implemented by the dialect that qualifies the table names such as by the database name.
+type TableQuoter interface {
+	// QuoteTable returns the quoted table name that can be used in the SQLs.
+	QuoteTable(name string) string
+}
+
+// Placeholderer is implemented by the dialect whose placeholders of the query parameters are not "?".
+type Placeholderer interface {
+	// Placeholder returns the placeholder of the i-th query parameter. i starts from 1.
+	Placeholder(i int) string
+}
+
+// VarcharPromoter is implemented by the dialect that limits the length of VARCHAR.
+type VarcharPromoter interface {
+	// PromoteVarchar returns the TEXT type that can hold the characters of typ in the charset of options
+	// if typ is VARCHAR that exceeds the limit. It returns false if typ is not promoted.
+	PromoteVarchar(typ string, options TableOptions) (string, bool)
+}
+
+// TableCommenter is implemented by the dialect that can retrieve the comments of the tables.
+type TableCommenter interface {
+	// TableComments returns the comments of the tables keyed by the table name.
+	// The tables that have no comment are not contained.
+	TableComments() (map[string]string, error)
+}
+
+// TableOptioner is implemented by the dialect that can retrieve and alter the options of the tables such as the storage engine.
+type TableOptioner interface {
+	// TableOptions returns the options of the tables keyed by the table name.
+	// If no tables are given, the options of all tables are returned.
+	TableOptions(tables ...string) (map[string]TableOptions, error)
+
+	// AlterTableOptionsSQL returns the SQLs to change the options of the table to options.
+	// The empty options are left as they are.
+	AlterTableOptionsSQL(table string, options TableOptions) []string
+}
+
+// IdentifierValidator is implemented by the dialect that restricts the identifiers such as the table names and the column names.
+type IdentifierValidator interface {
+	// MaxIdentifierLength returns the maximum length of the identifiers.
+	MaxIdentifierLength() int
+
+	// IsReservedWord reports whether name is a reserved word of the database. The comparison is case-insensitive.
+	IsReservedWord(name string) bool
+}
+
+// MultiStatementExecer is implemented by the dialect that can execute the multiple statements in a single call.
+type MultiStatementExecer interface {
+	// SupportsMultiStatements reports whether the connection permits the multiple statements in a single call.
+	SupportsMultiStatements() bool
+
+	// ExecMultiStatements executes sqls in a single call.
+	// It returns the number of the statements that were executed successfully,
+	// which tells the failed statement if the error is not nil.
+	ExecMultiStatements(sqls []string) (int, error)
+}
+
+// ViewLister is implemented by the dialect that can distinguish the views from the tables.
+type ViewLister interface {
+	// Views returns the names of the views in the database.
+	Views() ([]string, error)
+}
+
+// SessionPinner is implemented by the dialect that can execute the statements on a single database session.
+type SessionPinner interface {
+	// Pin returns the dialect that executes all statements on a single connection, and the function to release the connection.
+	// It returns ErrNoSingleConn if the connection cannot be taken from the database.
+	Pin(ctx context.Context) (d Dialect, release func() error, err error)
+}
+
+// ErrNoSingleConn is returned when the statements must be executed on a single connection,
+// but the DB given to the dialect is neither *sql.Conn nor able to return *sql.Conn like *sql.DB.
+var ErrNoSingleConn = errors.New("migu: the database cannot provide a single connection")
+
+// Shadower is implemented by the dialect that can validate the statements on a shadow database.
+type Shadower interface {
+	// ExecShadow creates the shadow database that has the same schema as the current database, executes sqls on it, and drops it.
+	// If a statement fails, ExecShadow returns the index of the statement with the error. Otherwise the index is -1.
+	ExecShadow(sqls []string) (int, error)
+}
+
+// Archiver is implemented by the dialect that can archive tables and columns instead of dropping them.
+type Archiver interface {
+	// RenameTableSQL returns the SQLs to rename the table from oldName to newName.
+	RenameTableSQL(oldName, newName string) []string
+
+	// ArchiveColumnSQL returns the SQLs to copy the data of the column with the primary key columns into the new table named archiveTable.
+	ArchiveColumnSQL(archiveTable string, field Field, primaryKeys []string) []string
+}
+
+// HistoryStore is implemented by the dialect that can record the applied statements into the history table.
+type HistoryStore interface {
+	// EnsureHistoryTable creates the history table if it does not exist.
+	EnsureHistoryTable(table string) error
+
+	// AppliedChecksums returns the set of checksums of History recorded in the history table.
+	AppliedChecksums(table string) (map[string]struct{}, error)
+
+	// RecordHistory records the applied statement into the history table.
+	RecordHistory(table string, history History) error
+}
+
+// HistorySQLer is implemented by the HistoryStore that can record the history by a statement.
+// The statement is executed in the same transaction as the applied statement.
+type HistorySQLer interface {
+	// RecordHistorySQL returns the statement and its arguments to record history into the history table.
+	RecordHistorySQL(table string, history History) (string, []interface{})
+}
+
+// History is a record of the applied statement.
+type History struct {
+	Checksum  string
+	SQL       string
+	AppliedAt time.Time
+	Duration  time.Duration
+}
+
+// AuditStore is implemented by the dialect that can record the applied statements with the metadata into the audit table.
+// Unlike the history table, the audit table keeps all records even if the same statement is applied again.
+type AuditStore interface {
+	// EnsureAuditTable creates the audit table if it does not exist.
+	EnsureAuditTable(table string) error
+
+	// RecordAudit appends the record of the applied statement to the audit table.
+	RecordAudit(table string, audit Audit) error
+
+	// Audits returns the records of the audit table in order of application.
+	// Only the records of targetTable and column are returned if they are not empty.
+	Audits(table, targetTable, column string) ([]Audit, error)
+}
+
+// Audit is a record of the applied statement with the metadata of the caller.
+type Audit struct {
+	Kind       string
+	Table      string
+	Column     string
+	SQL        string
+	AppliedAt  time.Time
+	Duration   time.Duration
+	User       string
+	Host       string
+	AppVersion string
+}
+
 type Table struct {
 	Name        string
 	Fields      []Field
 	PrimaryKeys []string
+	Options     TableOptions
 	Option      string
 }
 
+// TableOptions is the options of the table that migu understands.
+// The empty options are not specified.
+type TableOptions struct {
+	Engine    string
+	Charset   string
+	Collate   string
+	RowFormat string
+}
+
+// IsZero reports whether no options are specified.
+func (o TableOptions) IsZero() bool {
+	return o == TableOptions{}
+}
+
 type Field struct {
 	Table         string
 	Name          string
@@ -1,18 +1,41 @@
 package dialect
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
 )
 
-var _ PrimaryKeyModifier = &MySQL{}
+var (
+	_ PrimaryKeyModifier   = &MySQL{}
+	_ HistoryStore         = &MySQL{}
+	_ HistorySQLer         = &MySQL{}
+	_ AuditStore           = &MySQL{}
+	_ Archiver             = &MySQL{}
+	_ Retryable            = &MySQL{}
+	_ Shadower             = &MySQL{}
+	_ RowCounter           = &MySQL{}
+	_ Seeder               = &MySQL{}
+	_ TableCommenter       = &MySQL{}
+	_ TableOptioner        = &MySQL{}
+	_ TableQuoter          = &MySQL{}
+	_ ViewLister           = &MySQL{}
+	_ SessionPinner        = &MySQL{}
+	_ IdentifierValidator  = &MySQL{}
+	_ MultiStatementExecer = &MySQL{}
+)
 
 var (
 	mysqlColumnTypes = []*ColumnType{
 		{
-			Types:           []string{"VARCHAR", "TEXT", "MEDIUMTEXT", "LONGTEXT", "CHAR"},
+			Types:           []string{"VARCHAR", "TEXT", "MEDIUMTEXT", "LONGTEXT", "CHAR", "ENUM", "SET"},
 			GoTypes:         []string{"string"},
 			GoNullableTypes: []string{"*string", "sql.NullString"},
 		},
@@ -61,24 +84,40 @@ var (
 )
 
 type MySQL struct {
-	db              *sql.DB
+	db              DB
+	conn            DB
 	dbName          string
 	version         *mysqlVersion
 	opt             *option
 	columnTypeMap   map[string]*ColumnType
 	nullableTypeMap map[string]struct{}
+
+	// multiStatements is shared with the pinned copies, since they are the connections of the same DB.
+	multiStatements *mysqlMultiStatements
+}
+
+// mysqlMultiStatements is the result of the probe whether the connection permits the multiple statements.
+type mysqlMultiStatements struct {
+	once      sync.Once
+	supported bool
 }
 
-func NewMySQL(db *sql.DB, opts ...Option) Dialect {
+// NewMySQL returns the dialect for MySQL and MariaDB that uses db.
+// db is usually *sql.DB, but can be the other implementation of DB such as *sql.Conn or a wrapper of *sql.DB for the tests.
+// db can be nil if the dialect is used only for generating SQLs such as by migu.DiffFiles.
+func NewMySQL(db DB, opts ...Option) Dialect {
 	d := &MySQL{
 		db:              db,
+		conn:            db,
 		opt:             newOption(),
 		columnTypeMap:   map[string]*ColumnType{},
 		nullableTypeMap: map[string]struct{}{},
+		multiStatements: &mysqlMultiStatements{},
 	}
 	for _, o := range opts {
 		o(d.opt)
 	}
+	d.dbName = d.opt.database
 	for _, types := range [][]*ColumnType{mysqlColumnTypes, d.opt.columnTypes} {
 		for _, t := range types {
 			for _, tt := range t.allGoTypes() {
@@ -101,50 +140,71 @@ func (d *MySQL) ColumnSchema(tables ...string) ([]ColumnSchema, error) {
 	if err != nil {
 		return nil, err
 	}
-	indexMap, err := d.getIndexMap()
-	if err != nil {
-		return nil, err
-	}
 	parts := []string{
 		"SELECT",
-		"  TABLE_NAME,",
-		"  COLUMN_NAME,",
-		"  COLUMN_DEFAULT,",
-		"  IS_NULLABLE,",
-		"  DATA_TYPE,",
-		"  CHARACTER_MAXIMUM_LENGTH,",
-		"  CHARACTER_OCTET_LENGTH,",
-		"  NUMERIC_PRECISION,",
-		"  NUMERIC_SCALE,",
-		"  DATETIME_PRECISION,",
-		"  COLUMN_TYPE,",
-		"  COLUMN_KEY,",
-		"  EXTRA,",
-		"  COLUMN_COMMENT",
-		"FROM information_schema.COLUMNS",
-		"WHERE TABLE_SCHEMA = ?",
+		"  c.TABLE_NAME,",
+		"  c.COLUMN_NAME,",
+		"  c.COLUMN_DEFAULT,",
+		"  c.IS_NULLABLE,",
+		"  c.DATA_TYPE,",
+		"  c.CHARACTER_MAXIMUM_LENGTH,",
+		"  c.CHARACTER_OCTET_LENGTH,",
+		"  c.NUMERIC_PRECISION,",
+		"  c.NUMERIC_SCALE,",
+		"  c.DATETIME_PRECISION,",
+		"  c.COLUMN_TYPE,",
+		"  c.COLUMN_KEY,",
+		"  c.EXTRA,",
+		"  c.COLUMN_COMMENT,",
+		"  s.NON_UNIQUE,",
+		"  s.INDEX_NAME,",
+		"  s.SEQ_IN_INDEX",
 	}
+	// STATISTICS is also restricted by the constant conditions, so that only the indexes of
+	// the given tables are read instead of the indexes of all databases.
+	var placeholder string
 	args := []interface{}{dbname}
 	if len(tables) > 0 {
-		placeholder := strings.Repeat(",?", len(tables))
+		placeholder = strings.Repeat(",?", len(tables))
 		placeholder = placeholder[1:] // truncate the heading comma.
-		parts = append(parts, fmt.Sprintf("AND TABLE_NAME IN (%s)", placeholder))
 		for _, t := range tables {
 			args = append(args, t)
 		}
 	}
-	parts = append(parts, "ORDER BY TABLE_NAME, ORDINAL_POSITION")
+	fkArgs := args
+	args = append(args, args...)
+	cond := func(alias string) string {
+		c := alias + ".TABLE_SCHEMA = ?"
+		if placeholder != "" {
+			c += fmt.Sprintf(" AND %s.TABLE_NAME IN (%s)", alias, placeholder)
+		}
+		return c
+	}
+	parts = append(parts,
+		"FROM information_schema.COLUMNS AS c",
+		"LEFT JOIN information_schema.STATISTICS AS s",
+		"  ON "+cond("s")+" AND s.TABLE_NAME = c.TABLE_NAME AND s.COLUMN_NAME = c.COLUMN_NAME",
+		"WHERE "+cond("c"),
+		"ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION, s.INDEX_NAME",
+	)
 	query := strings.Join(parts, "\n")
-	rows, err := d.db.Query(query, args...)
+	rows, err := d.conn.QueryContext(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	// The column appears in the consecutive rows as many times as the number of its indexes.
 	var schemas []ColumnSchema
+	var last *mysqlColumnSchema
 	for rows.Next() {
 		schema := &mysqlColumnSchema{
 			version: version,
 		}
+		var (
+			nonUnique  sql.NullInt64
+			indexName  sql.NullString
+			seqInIndex sql.NullInt64
+		)
 		if err := rows.Scan(
 			&schema.tableName,
 			&schema.columnName,
@@ -160,32 +220,88 @@ func (d *MySQL) ColumnSchema(tables ...string) ([]ColumnSchema, error) {
 			&schema.columnKey,
 			&schema.extra,
 			&schema.columnComment,
+			&nonUnique,
+			&indexName,
+			&seqInIndex,
 		); err != nil {
 			return nil, err
 		}
-		if tableIndex, exists := indexMap[schema.tableName]; exists {
-			if info, exists := tableIndex[schema.columnName]; exists {
-				schema.nonUnique = info.NonUnique
-				schema.indexName = info.IndexName
-			}
+		if last != nil && last.tableName == schema.tableName && last.columnName == schema.columnName {
+			schema = last
+		} else {
+			schemas = append(schemas, schema)
+			last = schema
+		}
+		if indexName.Valid {
+			schema.indexes = append(schema.indexes, mysqlIndexInfo{
+				NonUnique:  nonUnique.Int64,
+				IndexName:  indexName.String,
+				SeqInIndex: seqInIndex.Int64,
+			})
 		}
-		schemas = append(schemas, schema)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	fks, err := d.foreignKeys(cond("k"), fkArgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, schema := range schemas {
+		s := schema.(*mysqlColumnSchema)
+		s.foreignKey = fks[s.tableName+"."+s.columnName]
+	}
 	return schemas, nil
 }
 
+// foreignKeys returns the referenced columns as "<table>.<column>" keyed by the referencing columns as "<table>.<column>".
+// Only the foreign keys to the tables in the same database are returned, because `fk` tag cannot refer to the other databases.
+func (d *MySQL) foreignKeys(cond string, args []interface{}) (map[string]string, error) {
+	query := strings.Join([]string{
+		"SELECT",
+		"  k.TABLE_NAME,",
+		"  k.COLUMN_NAME,",
+		"  k.REFERENCED_TABLE_NAME,",
+		"  k.REFERENCED_COLUMN_NAME",
+		"FROM information_schema.KEY_COLUMN_USAGE AS k",
+		"WHERE " + cond + " AND k.REFERENCED_TABLE_SCHEMA = k.TABLE_SCHEMA",
+		"ORDER BY k.TABLE_NAME, k.COLUMN_NAME, k.CONSTRAINT_NAME",
+	}, "\n")
+	rows, err := d.conn.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fks := map[string]string{}
+	for rows.Next() {
+		var table, column, refTable, refColumn string
+		if err := rows.Scan(&table, &column, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		// The column that has multiple foreign keys refers to the first one.
+		if _, ok := fks[table+"."+column]; !ok {
+			fks[table+"."+column] = refTable + "." + refColumn
+		}
+	}
+	return fks, rows.Err()
+}
+
 func (d *MySQL) ColumnType(name string) string {
 	var unsigned bool
 	if t, ok := d.columnTypeMap[name]; ok {
 		name, _, unsigned, _ = t.findType(name)
 	}
-	name = d.defaultColumnType(name)
+	name = d.defaultColumnType(canonicalMySQLType(name))
 	if unsigned {
 		name += " UNSIGNED"
 	}
+	if i := strings.IndexByte(name, '('); i >= 0 {
+		switch typ := strings.ToUpper(name[:i]); typ {
+		case "ENUM", "SET":
+			// The members are case-sensitive.
+			return typ + name[i:]
+		}
+	}
 	return strings.ToUpper(name)
 }
 
@@ -219,10 +335,82 @@ func (d *MySQL) ImportPackage(schema ColumnSchema) string {
 	return ""
 }
 
+// mysqlReservedWords is the reserved words of MySQL 8.0.
+// See https://dev.mysql.com/doc/refman/8.0/en/keywords.html
+var mysqlReservedWords = map[string]struct{}{
+	"ACCESSIBLE": {}, "ADD": {}, "ALL": {}, "ALTER": {}, "ANALYZE": {}, "AND": {}, "AS": {},
+	"ASC": {}, "ASENSITIVE": {}, "BEFORE": {}, "BETWEEN": {}, "BIGINT": {}, "BINARY": {}, "BLOB": {},
+	"BOTH": {}, "BY": {}, "CALL": {}, "CASCADE": {}, "CASE": {}, "CHANGE": {}, "CHAR": {},
+	"CHARACTER": {}, "CHECK": {}, "COLLATE": {}, "COLUMN": {}, "CONDITION": {}, "CONSTRAINT": {},
+	"CONTINUE": {}, "CONVERT": {}, "CREATE": {}, "CROSS": {}, "CUBE": {}, "CUME_DIST": {},
+	"CURRENT_DATE": {}, "CURRENT_TIME": {}, "CURRENT_TIMESTAMP": {}, "CURRENT_USER": {}, "CURSOR": {},
+	"DATABASE": {}, "DATABASES": {}, "DAY_HOUR": {}, "DAY_MICROSECOND": {}, "DAY_MINUTE": {},
+	"DAY_SECOND": {}, "DEC": {}, "DECIMAL": {}, "DECLARE": {}, "DEFAULT": {}, "DELAYED": {},
+	"DELETE": {}, "DENSE_RANK": {}, "DESC": {}, "DESCRIBE": {}, "DETERMINISTIC": {}, "DISTINCT": {},
+	"DISTINCTROW": {}, "DIV": {}, "DOUBLE": {}, "DROP": {}, "DUAL": {}, "EACH": {}, "ELSE": {},
+	"ELSEIF": {}, "EMPTY": {}, "ENCLOSED": {}, "ESCAPED": {}, "EXCEPT": {}, "EXISTS": {}, "EXIT": {},
+	"EXPLAIN": {}, "FALSE": {}, "FETCH": {}, "FIRST_VALUE": {}, "FLOAT": {}, "FLOAT4": {},
+	"FLOAT8": {}, "FOR": {}, "FORCE": {}, "FOREIGN": {}, "FROM": {}, "FULLTEXT": {}, "FUNCTION": {},
+	"GENERATED": {}, "GET": {}, "GRANT": {}, "GROUP": {}, "GROUPING": {}, "GROUPS": {}, "HAVING": {},
+	"HIGH_PRIORITY": {}, "HOUR_MICROSECOND": {}, "HOUR_MINUTE": {}, "HOUR_SECOND": {}, "IF": {},
+	"IGNORE": {}, "IN": {}, "INDEX": {}, "INFILE": {}, "INNER": {}, "INOUT": {}, "INSENSITIVE": {},
+	"INSERT": {}, "INT": {}, "INT1": {}, "INT2": {}, "INT3": {}, "INT4": {}, "INT8": {},
+	"INTEGER": {}, "INTERSECT": {}, "INTERVAL": {}, "INTO": {}, "IO_AFTER_GTIDS": {},
+	"IO_BEFORE_GTIDS": {}, "IS": {}, "ITERATE": {}, "JOIN": {}, "JSON_TABLE": {}, "KEY": {},
+	"KEYS": {}, "KILL": {}, "LAG": {}, "LAST_VALUE": {}, "LATERAL": {}, "LEAD": {}, "LEADING": {},
+	"LEAVE": {}, "LEFT": {}, "LIKE": {}, "LIMIT": {}, "LINEAR": {}, "LINES": {}, "LOAD": {},
+	"LOCALTIME": {}, "LOCALTIMESTAMP": {}, "LOCK": {}, "LONG": {}, "LONGBLOB": {}, "LONGTEXT": {},
+	"LOOP": {}, "LOW_PRIORITY": {}, "MASTER_BIND": {}, "MASTER_SSL_VERIFY_SERVER_CERT": {},
+	"MATCH": {}, "MAXVALUE": {}, "MEDIUMBLOB": {}, "MEDIUMINT": {}, "MEDIUMTEXT": {}, "MIDDLEINT": {},
+	"MINUTE_MICROSECOND": {}, "MINUTE_SECOND": {}, "MOD": {}, "MODIFIES": {}, "NATURAL": {},
+	"NOT": {}, "NO_WRITE_TO_BINLOG": {}, "NTH_VALUE": {}, "NTILE": {}, "NULL": {}, "NUMERIC": {},
+	"OF": {}, "ON": {}, "OPTIMIZE": {}, "OPTIMIZER_COSTS": {}, "OPTION": {}, "OPTIONALLY": {},
+	"OR": {}, "ORDER": {}, "OUT": {}, "OUTER": {}, "OUTFILE": {}, "OVER": {}, "PARTITION": {},
+	"PERCENT_RANK": {}, "PRECISION": {}, "PRIMARY": {}, "PROCEDURE": {}, "PURGE": {}, "RANGE": {},
+	"RANK": {}, "READ": {}, "READS": {}, "READ_WRITE": {}, "REAL": {}, "RECURSIVE": {},
+	"REFERENCES": {}, "REGEXP": {}, "RELEASE": {}, "RENAME": {}, "REPEAT": {}, "REPLACE": {},
+	"REQUIRE": {}, "RESIGNAL": {}, "RESTRICT": {}, "RETURN": {}, "REVOKE": {}, "RIGHT": {},
+	"RLIKE": {}, "ROW": {}, "ROWS": {}, "ROW_NUMBER": {}, "SCHEMA": {}, "SCHEMAS": {},
+	"SECOND_MICROSECOND": {}, "SELECT": {}, "SENSITIVE": {}, "SEPARATOR": {}, "SET": {}, "SHOW": {},
+	"SIGNAL": {}, "SMALLINT": {}, "SPATIAL": {}, "SPECIFIC": {}, "SQL": {}, "SQLEXCEPTION": {},
+	"SQLSTATE": {}, "SQLWARNING": {}, "SQL_BIG_RESULT": {}, "SQL_CALC_FOUND_ROWS": {},
+	"SQL_SMALL_RESULT": {}, "SSL": {}, "STARTING": {}, "STORED": {}, "STRAIGHT_JOIN": {},
+	"SYSTEM": {}, "TABLE": {}, "TERMINATED": {}, "THEN": {}, "TINYBLOB": {}, "TINYINT": {},
+	"TINYTEXT": {}, "TO": {}, "TRAILING": {}, "TRIGGER": {}, "TRUE": {}, "UNDO": {}, "UNION": {},
+	"UNIQUE": {}, "UNLOCK": {}, "UNSIGNED": {}, "UPDATE": {}, "USAGE": {}, "USE": {}, "USING": {},
+	"UTC_DATE": {}, "UTC_TIME": {}, "UTC_TIMESTAMP": {}, "VALUES": {}, "VARBINARY": {}, "VARCHAR": {},
+	"VARCHARACTER": {}, "VARYING": {}, "VIRTUAL": {}, "WHEN": {}, "WHERE": {}, "WHILE": {},
+	"WINDOW": {}, "WITH": {}, "WRITE": {}, "XOR": {}, "YEAR_MONTH": {}, "ZEROFILL": {},
+}
+
+// MaxIdentifierLength returns the maximum length of the identifiers of MySQL.
+func (d *MySQL) MaxIdentifierLength() int {
+	return 64
+}
+
+// IsReservedWord reports whether name is a reserved word of MySQL.
+func (d *MySQL) IsReservedWord(name string) bool {
+	_, ok := mysqlReservedWords[strings.ToUpper(name)]
+	return ok
+}
+
 func (d *MySQL) Quote(s string) string {
 	return fmt.Sprintf("`%s`", strings.Replace(s, "`", "``", -1))
 }
 
+// QuoteTable returns the quoted table name.
+// If the database is given by WithDatabase, the table name is qualified by the database name. (e.g. `db`.`user`)
+func (d *MySQL) QuoteTable(name string) string {
+	return d.quoteTable(name)
+}
+
+func (d *MySQL) quoteTable(name string) string {
+	if d.opt.database == "" {
+		return d.Quote(name)
+	}
+	return d.Quote(d.opt.database) + "." + d.Quote(name)
+}
+
 func (d *MySQL) QuoteString(s string) string {
 	return fmt.Sprintf("'%s'", strings.Replace(s, "'", "''", -1))
 }
@@ -239,25 +427,71 @@ func (d *MySQL) CreateTableSQL(table Table) []string {
 		}
 		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pkColumns, ", ")))
 	}
-	query := fmt.Sprintf("CREATE TABLE %s (\n"+
+	query := fmt.Sprintf("CREATE TABLE %s%s (\n"+
 		"  %s\n"+
-		")", d.Quote(table.Name), strings.Join(columns, ",\n  "))
+		")", d.ifNotExists(), d.quoteTable(table.Name), strings.Join(columns, ",\n  "))
+	if options := d.tableOptionsSQL(table.Options); options != "" {
+		query += " " + options
+	}
 	if table.Option != "" {
 		query += " " + table.Option
 	}
 	return []string{query}
 }
 
+// tableOptionsSQL returns the table options clause of options, such as "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4".
+func (d *MySQL) tableOptionsSQL(options TableOptions) string {
+	var clauses []string
+	if options.Engine != "" {
+		clauses = append(clauses, "ENGINE="+options.Engine)
+	}
+	if options.Charset != "" {
+		clauses = append(clauses, "DEFAULT CHARSET="+options.Charset)
+	}
+	if options.Collate != "" {
+		clauses = append(clauses, "COLLATE="+options.Collate)
+	}
+	if options.RowFormat != "" {
+		clauses = append(clauses, "ROW_FORMAT="+options.RowFormat)
+	}
+	return strings.Join(clauses, " ")
+}
+
+func (d *MySQL) AlterTableOptionsSQL(table string, options TableOptions) []string {
+	clauses := d.tableOptionsSQL(options)
+	if clauses == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s %s", d.quoteTable(table), clauses)}
+}
+
+func (d *MySQL) DropTableSQL(table Table) []string {
+	return []string{fmt.Sprintf("DROP TABLE %s%s", d.ifExists(), d.quoteTable(table.Name))}
+}
+
+func (d *MySQL) RenameTableSQL(oldName, newName string) []string {
+	return []string{fmt.Sprintf("RENAME TABLE %s TO %s", d.quoteTable(oldName), d.quoteTable(newName))}
+}
+
+func (d *MySQL) ArchiveColumnSQL(archiveTable string, field Field, primaryKeys []string) []string {
+	columns := make([]string, 0, len(primaryKeys)+1)
+	for _, pk := range primaryKeys {
+		columns = append(columns, d.Quote(pk))
+	}
+	columns = append(columns, d.Quote(field.Name))
+	return []string{fmt.Sprintf("CREATE TABLE %s AS SELECT %s FROM %s", d.quoteTable(archiveTable), strings.Join(columns, ", "), d.quoteTable(field.Table))}
+}
+
 func (d *MySQL) AddColumnSQL(field Field) []string {
-	return []string{fmt.Sprintf("ALTER TABLE %s ADD %s", d.Quote(field.Table), d.columnSQL(field))}
+	return []string{fmt.Sprintf("ALTER TABLE %s ADD %s", d.quoteTable(field.Table), d.columnSQL(field))}
 }
 
 func (d *MySQL) DropColumnSQL(field Field) []string {
-	return []string{fmt.Sprintf("ALTER TABLE %s DROP %s", d.Quote(field.Table), d.Quote(field.Name))}
+	return []string{fmt.Sprintf("ALTER TABLE %s DROP %s", d.quoteTable(field.Table), d.Quote(field.Name))}
 }
 
 func (d *MySQL) ModifyColumnSQL(oldField, newField Field) []string {
-	return []string{fmt.Sprintf("ALTER TABLE %s CHANGE %s %s", d.Quote(newField.Table), d.Quote(oldField.Name), d.columnSQL(newField))}
+	return []string{fmt.Sprintf("ALTER TABLE %s CHANGE %s %s", d.quoteTable(newField.Table), d.Quote(oldField.Name), d.columnSQL(newField))}
 }
 
 func (d *MySQL) ModifyPrimaryKeySQL(oldPrimaryKeys, newPrimaryKeys []Field) []string {
@@ -278,7 +512,7 @@ func (d *MySQL) ModifyPrimaryKeySQL(oldPrimaryKeys, newPrimaryKeys []Field) []st
 		}
 		specs = append(specs, fmt.Sprintf("ADD PRIMARY KEY (%s)", strings.Join(pkColumns, ", ")))
 	}
-	return []string{fmt.Sprintf("ALTER TABLE %s %s", d.Quote(tableName), strings.Join(specs, ", "))}
+	return []string{fmt.Sprintf("ALTER TABLE %s %s", d.quoteTable(tableName), strings.Join(specs, ", "))}
 }
 
 func (d *MySQL) CreateIndexSQL(index Index) []string {
@@ -287,16 +521,44 @@ func (d *MySQL) CreateIndexSQL(index Index) []string {
 		columns[i] = d.Quote(c)
 	}
 	indexName := d.Quote(index.Name)
-	tableName := d.Quote(index.Table)
+	tableName := d.quoteTable(index.Table)
 	column := strings.Join(columns, ",")
+	var guard string
+	if d.isMariaDB() {
+		guard = d.ifNotExists()
+	}
 	if index.Unique {
-		return []string{fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)", indexName, tableName, column)}
+		return []string{fmt.Sprintf("CREATE UNIQUE INDEX %s%s ON %s (%s)", guard, indexName, tableName, column)}
 	}
-	return []string{fmt.Sprintf("CREATE INDEX %s ON %s (%s)", indexName, tableName, column)}
+	return []string{fmt.Sprintf("CREATE INDEX %s%s ON %s (%s)", guard, indexName, tableName, column)}
 }
 
 func (d *MySQL) DropIndexSQL(index Index) []string {
-	return []string{fmt.Sprintf("DROP INDEX %s ON %s", d.Quote(index.Name), d.Quote(index.Table))}
+	var guard string
+	if d.isMariaDB() {
+		guard = d.ifExists()
+	}
+	return []string{fmt.Sprintf("DROP INDEX %s%s ON %s", guard, d.Quote(index.Name), d.quoteTable(index.Table))}
+}
+
+func (d *MySQL) ifExists() string {
+	if d.opt.ifExists {
+		return "IF EXISTS "
+	}
+	return ""
+}
+
+func (d *MySQL) ifNotExists() string {
+	if d.opt.ifExists {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+// isMariaDB returns whether the database is MariaDB.
+// It returns false if the version of the database has not been detected yet.
+func (d *MySQL) isMariaDB() bool {
+	return d.version != nil && d.version.Name == "MariaDB"
 }
 
 func (d *MySQL) columnSQL(f Field) string {
@@ -332,8 +594,44 @@ func (d *MySQL) isTextType(f Field) bool {
 	return false
 }
 
+// Pin returns the copy of d that executes all statements on a single connection of the database.
+// The statements of the shadow database are still executed on another connection.
+// If the DB given to NewMySQL cannot return a single connection, such as *sql.Conn, it is regarded as a single connection.
+// The dialect that has been pinned is returned as it is, so that the caller can execute the statements such as
+// the session settings on the pinned session before giving it to migu.Sync or (*migu.MigrationPlan).Apply.
+func (d *MySQL) Pin(ctx context.Context) (Dialect, func() error, error) {
+	if _, ok := d.conn.(*sql.Conn); ok {
+		return d, func() error { return nil }, nil
+	}
+	conn, release, err := d.singleConn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	pinned := *d
+	pinned.conn = conn
+	return &pinned, release, nil
+}
+
+// singleConn returns a single connection of the DB given to NewMySQL, and the function to release it.
+// If the DB is *sql.Conn, it is returned as it is. It returns ErrNoSingleConn if the DB cannot return a single connection,
+// since the pool may execute the statements on the different sessions.
+func (d *MySQL) singleConn(ctx context.Context) (DB, func() error, error) {
+	if conn, ok := d.db.(*sql.Conn); ok {
+		return conn, func() error { return nil }, nil
+	}
+	c, ok := d.db.(mysqlConnector)
+	if !ok {
+		return nil, nil, ErrNoSingleConn
+	}
+	conn, err := c.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, conn.Close, nil
+}
+
 func (d *MySQL) Begin() (Transactioner, error) {
-	tx, err := d.db.Begin()
+	tx, err := d.conn.BeginTx(context.Background(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -342,6 +640,453 @@ func (d *MySQL) Begin() (Transactioner, error) {
 	}, nil
 }
 
+// SupportsMultiStatements reports whether the connection permits the multiple statements,
+// which requires multiStatements=true in DSN of github.com/go-sql-driver/mysql.
+// The connection is probed only once, and the result is reused by the later calls.
+func (d *MySQL) SupportsMultiStatements() bool {
+	m := d.multiStatements
+	m.once.Do(func() {
+		_, err := d.conn.ExecContext(context.Background(), "DO 1; DO 1")
+		m.supported = err == nil
+	})
+	return m.supported
+}
+
+// ExecMultiStatements executes sqls that are joined by semicolons in a single call.
+// Each statement is followed by a SELECT of its sequence number, so that the number of the executed statements
+// can be counted from the result sets even if a statement in the middle fails.
+func (d *MySQL) ExecMultiStatements(sqls []string) (int, error) {
+	stmts := make([]string, 0, len(sqls)*2)
+	for i, stmt := range sqls {
+		stmts = append(stmts, stmt, fmt.Sprintf("SELECT %d", i+1))
+	}
+	rows, err := d.conn.QueryContext(context.Background(), strings.Join(stmts, ";\n"))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var executed int
+	for {
+		for rows.Next() {
+			if err := rows.Scan(&executed); err != nil {
+				return executed, err
+			}
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return executed, rows.Err()
+}
+
+// IsRetryable returns true if err is a lock wait timeout (1205) or a deadlock (1213).
+func (d *MySQL) IsRetryable(err error) bool {
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Number {
+	case 1205, 1213:
+		return true
+	}
+	return false
+}
+
+func (d *MySQL) UpsertSQL(table string, columns, values, primaryKeys []string) []string {
+	pkMap := make(map[string]struct{}, len(primaryKeys))
+	for _, pk := range primaryKeys {
+		pkMap[pk] = struct{}{}
+	}
+	quoted := make([]string, len(columns))
+	var updates []string
+	for i, column := range columns {
+		quoted[i] = d.Quote(column)
+		if _, ok := pkMap[column]; !ok {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quoted[i], quoted[i]))
+		}
+	}
+	if len(updates) == 0 {
+		updates = append(updates, fmt.Sprintf("%s = %s", d.Quote(primaryKeys[0]), d.Quote(primaryKeys[0])))
+	}
+	return []string{fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s", d.quoteTable(table), strings.Join(quoted, ", "), strings.Join(values, ", "), strings.Join(updates, ", "))}
+}
+
+func (d *MySQL) PruneSQL(table string, primaryKeys []string, keys [][]string) []string {
+	if len(keys) == 0 {
+		return []string{fmt.Sprintf("DELETE FROM %s", d.quoteTable(table))}
+	}
+	columns := make([]string, len(primaryKeys))
+	for i, pk := range primaryKeys {
+		columns[i] = d.Quote(pk)
+	}
+	tuples := make([]string, len(keys))
+	for i, key := range keys {
+		tuples[i] = strings.Join(key, ", ")
+		if len(key) > 1 {
+			tuples[i] = "(" + tuples[i] + ")"
+		}
+	}
+	column := strings.Join(columns, ", ")
+	if len(columns) > 1 {
+		column = "(" + column + ")"
+	}
+	return []string{fmt.Sprintf("DELETE FROM %s WHERE %s NOT IN (%s)", d.quoteTable(table), column, strings.Join(tuples, ", "))}
+}
+
+func (d *MySQL) TableComments() (map[string]string, error) {
+	dbname, err := d.currentDBName()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.conn.QueryContext(context.Background(), "SELECT TABLE_NAME, TABLE_COMMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' AND TABLE_COMMENT <> ''", dbname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := map[string]string{}
+	for rows.Next() {
+		var name, comment string
+		if err := rows.Scan(&name, &comment); err != nil {
+			return nil, err
+		}
+		comments[name] = comment
+	}
+	return comments, rows.Err()
+}
+
+func (d *MySQL) TableOptions(tables ...string) (map[string]TableOptions, error) {
+	dbname, err := d.currentDBName()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT t.TABLE_NAME, t.ENGINE, c.CHARACTER_SET_NAME, t.TABLE_COLLATION, t.ROW_FORMAT" +
+		" FROM information_schema.TABLES AS t" +
+		" LEFT JOIN information_schema.COLLATIONS AS c ON c.COLLATION_NAME = t.TABLE_COLLATION" +
+		" WHERE t.TABLE_SCHEMA = ? AND t.TABLE_TYPE = 'BASE TABLE'"
+	args := []interface{}{dbname}
+	if len(tables) > 0 {
+		query += " AND t.TABLE_NAME IN (" + strings.Repeat(",?", len(tables))[1:] + ")"
+		for _, t := range tables {
+			args = append(args, t)
+		}
+	}
+	rows, err := d.conn.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	options := map[string]TableOptions{}
+	for rows.Next() {
+		var (
+			name                                string
+			engine, charset, collate, rowFormat sql.NullString
+		)
+		if err := rows.Scan(&name, &engine, &charset, &collate, &rowFormat); err != nil {
+			return nil, err
+		}
+		options[name] = TableOptions{
+			Engine:    engine.String,
+			Charset:   charset.String,
+			Collate:   collate.String,
+			RowFormat: rowFormat.String,
+		}
+	}
+	return options, rows.Err()
+}
+
+func (d *MySQL) Views() ([]string, error) {
+	dbname, err := d.currentDBName()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.conn.QueryContext(context.Background(), "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'VIEW'", dbname)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var views []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		views = append(views, name)
+	}
+	return views, rows.Err()
+}
+
+func (d *MySQL) CountRows(query string) (int64, error) {
+	var n int64
+	if err := d.conn.QueryRowContext(context.Background(), query).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// ExecShadow executes sqls on the shadow database named "<database>_migu_shadow".
+// The tables of the shadow database are created by CREATE TABLE ... LIKE, so the data is not copied.
+//
+// The statements are executed on a single connection that switches to the shadow database by USE statement.
+// It returns ErrNoSingleConn if the connection cannot be taken, since the unqualified statements could be executed on the real database.
+func (d *MySQL) ExecShadow(sqls []string) (failed int, err error) {
+	ctx := context.Background()
+	conn, release, err := d.singleConn(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer release()
+	dbname, err := d.currentDBName()
+	if err != nil {
+		return -1, err
+	}
+	shadow := dbname + "_migu_shadow"
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", d.Quote(shadow))); err != nil {
+		return -1, err
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s", d.Quote(shadow))); err != nil {
+		return -1, err
+	}
+	defer func() {
+		if _, e := conn.ExecContext(ctx, fmt.Sprintf("USE %s", d.Quote(dbname))); e != nil && err == nil {
+			failed, err = -1, e
+		}
+		if _, e := conn.ExecContext(ctx, fmt.Sprintf("DROP DATABASE %s", d.Quote(shadow))); e != nil && err == nil {
+			failed, err = -1, e
+		}
+	}()
+	rows, err := conn.QueryContext(ctx, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", dbname)
+	if err != nil {
+		return -1, err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return -1, err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return -1, err
+	}
+	for _, table := range tables {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s.%s LIKE %s.%s", d.Quote(shadow), d.Quote(table), d.Quote(dbname), d.Quote(table))); err != nil {
+			return -1, err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf("USE %s", d.Quote(shadow))); err != nil {
+		return -1, err
+	}
+	for i, sql := range sqls {
+		if d.opt.database != "" {
+			// The table names are qualified by the database name, so they are replaced with the shadow database name.
+			sql = strings.Replace(sql, d.Quote(dbname)+".", d.Quote(shadow)+".", -1)
+		}
+		if _, err := conn.ExecContext(ctx, sql); err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
+
+func (d *MySQL) EnsureHistoryTable(table string) error {
+	_, err := d.conn.ExecContext(context.Background(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+		"  `checksum` CHAR(64) NOT NULL,\n"+
+		"  `statement` TEXT NOT NULL,\n"+
+		"  `applied_at` DATETIME(6) NOT NULL,\n"+
+		"  `duration_ms` BIGINT NOT NULL,\n"+
+		"  PRIMARY KEY (`checksum`)\n"+
+		")", d.quoteTable(table)))
+	return err
+}
+
+func (d *MySQL) AppliedChecksums(table string) (map[string]struct{}, error) {
+	rows, err := d.conn.QueryContext(context.Background(), fmt.Sprintf("SELECT `checksum` FROM %s", d.quoteTable(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	checksums := map[string]struct{}{}
+	for rows.Next() {
+		var checksum string
+		if err := rows.Scan(&checksum); err != nil {
+			return nil, err
+		}
+		checksums[checksum] = struct{}{}
+	}
+	return checksums, rows.Err()
+}
+
+func (d *MySQL) RecordHistory(table string, history History) error {
+	query, args := d.RecordHistorySQL(table, history)
+	_, err := d.conn.ExecContext(context.Background(), query, args...)
+	return err
+}
+
+func (d *MySQL) RecordHistorySQL(table string, history History) (string, []interface{}) {
+	return fmt.Sprintf("REPLACE INTO %s (`checksum`, `statement`, `applied_at`, `duration_ms`) VALUES (?, ?, ?, ?)", d.quoteTable(table)),
+		[]interface{}{history.Checksum, history.SQL, history.AppliedAt.UTC(), int64(history.Duration / time.Millisecond)}
+}
+
+func (d *MySQL) EnsureAuditTable(table string) error {
+	_, err := d.conn.ExecContext(context.Background(), fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n"+
+		"  `id` BIGINT NOT NULL AUTO_INCREMENT,\n"+
+		"  `kind` VARCHAR(64) NOT NULL,\n"+
+		"  `table_name` VARCHAR(64) NOT NULL,\n"+
+		"  `column_name` VARCHAR(64) NOT NULL,\n"+
+		"  `statement` TEXT NOT NULL,\n"+
+		"  `applied_at` DATETIME(6) NOT NULL,\n"+
+		"  `duration_ms` BIGINT NOT NULL,\n"+
+		"  `user` VARCHAR(255) NOT NULL,\n"+
+		"  `host` VARCHAR(255) NOT NULL,\n"+
+		"  `app_version` VARCHAR(255) NOT NULL,\n"+
+		"  PRIMARY KEY (`id`),\n"+
+		"  INDEX (`table_name`, `column_name`)\n"+
+		")", d.quoteTable(table)))
+	return err
+}
+
+func (d *MySQL) RecordAudit(table string, audit Audit) error {
+	_, err := d.conn.ExecContext(context.Background(), fmt.Sprintf("INSERT INTO %s (`kind`, `table_name`, `column_name`, `statement`, `applied_at`, `duration_ms`, `user`, `host`, `app_version`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", d.quoteTable(table)),
+		audit.Kind, audit.Table, audit.Column, audit.SQL, audit.AppliedAt.UTC(), int64(audit.Duration/time.Millisecond), audit.User, audit.Host, audit.AppVersion)
+	return err
+}
+
+func (d *MySQL) Audits(table, targetTable, column string) ([]Audit, error) {
+	query := fmt.Sprintf("SELECT `kind`, `table_name`, `column_name`, `statement`, CAST(`applied_at` AS CHAR), `duration_ms`, `user`, `host`, `app_version` FROM %s", d.quoteTable(table))
+	var (
+		conds []string
+		args  []interface{}
+	)
+	if targetTable != "" {
+		conds = append(conds, "`table_name` = ?")
+		args = append(args, targetTable)
+	}
+	if column != "" {
+		conds = append(conds, "`column_name` = ?")
+		args = append(args, column)
+	}
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	rows, err := d.conn.QueryContext(context.Background(), query+" ORDER BY `id`", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var audits []Audit
+	for rows.Next() {
+		var (
+			audit      Audit
+			appliedAt  string
+			durationMS int64
+		)
+		if err := rows.Scan(&audit.Kind, &audit.Table, &audit.Column, &audit.SQL, &appliedAt, &durationMS, &audit.User, &audit.Host, &audit.AppVersion); err != nil {
+			return nil, err
+		}
+		if audit.AppliedAt, err = time.Parse("2006-01-02 15:04:05.999999", appliedAt); err != nil {
+			return nil, err
+		}
+		audit.Duration = time.Duration(durationMS) * time.Millisecond
+		audits = append(audits, audit)
+	}
+	return audits, rows.Err()
+}
+
+const (
+	// mysqlMaxRowBytes is the maximum size of a row in bytes that also limits the length of VARCHAR.
+	mysqlMaxRowBytes = 65535
+
+	mysqlMaxMediumTextBytes = 1<<24 - 1
+)
+
+// mysqlCharsetBytes maps the charsets to the maximum bytes per character.
+// The charsets that are not contained, including utf8mb4 of the default, use 4 bytes.
+var mysqlCharsetBytes = map[string]int{
+	"armscii8": 1, "ascii": 1, "binary": 1, "cp1250": 1, "cp1251": 1, "cp1256": 1, "cp1257": 1, "cp850": 1,
+	"cp852": 1, "cp866": 1, "dec8": 1, "geostd8": 1, "greek": 1, "hebrew": 1, "hp8": 1, "keybcs2": 1,
+	"koi8r": 1, "koi8u": 1, "latin1": 1, "latin2": 1, "latin5": 1, "latin7": 1, "macce": 1, "macroman": 1,
+	"swe7": 1, "tis620": 1,
+	"big5": 2, "cp932": 2, "euckr": 2, "gb2312": 2, "gbk": 2, "sjis": 2, "ucs2": 2,
+	"eucjpms": 3, "ujis": 3, "utf8": 3, "utf8mb3": 3,
+}
+
+// MySQLMaxVarcharLength returns the maximum length of VARCHAR in the charset of options for MySQL.
+// The charset is assumed from the collation if only the collation is specified.
+func MySQLMaxVarcharLength(options TableOptions) int {
+	return mysqlMaxRowBytes / mysqlCharsetBytesOf(options)
+}
+
+func mysqlCharsetBytesOf(options TableOptions) int {
+	charset := options.Charset
+	if charset == "" {
+		charset = strings.SplitN(options.Collate, "_", 2)[0]
+	}
+	if n, ok := mysqlCharsetBytes[strings.ToLower(charset)]; ok {
+		return n
+	}
+	return 4
+}
+
+// PromoteVarchar implements VarcharPromoter.
+// The server rejects VARCHAR that exceeds the limit, so that the TEXT type that can hold the characters is returned instead.
+// (e.g. "VARCHAR(20000)" to "MEDIUMTEXT" in utf8mb4)
+func (d *MySQL) PromoteVarchar(typ string, options TableOptions) (string, bool) {
+	upper := strings.ToUpper(typ)
+	if !strings.HasPrefix(upper, "VARCHAR(") || !strings.HasSuffix(upper, ")") {
+		return typ, false
+	}
+	size, err := strconv.Atoi(upper[len("VARCHAR(") : len(upper)-1])
+	if err != nil || size <= MySQLMaxVarcharLength(options) {
+		return typ, false
+	}
+	if size*mysqlCharsetBytesOf(options) <= mysqlMaxMediumTextBytes {
+		return "MEDIUMTEXT", true
+	}
+	return "LONGTEXT", true
+}
+
+// mysqlTypeSynonyms maps the synonyms of the data types to the types that MySQL reports in information_schema.
+var mysqlTypeSynonyms = map[string]string{
+	"BOOL":             "TINYINT(1)",
+	"BOOLEAN":          "TINYINT(1)",
+	"INT1":             "TINYINT",
+	"INT2":             "SMALLINT",
+	"INT3":             "MEDIUMINT",
+	"MIDDLEINT":        "MEDIUMINT",
+	"INT4":             "INT",
+	"INTEGER":          "INT",
+	"INT8":             "BIGINT",
+	"DEC":              "DECIMAL",
+	"FIXED":            "DECIMAL",
+	"NUMERIC":          "DECIMAL",
+	"REAL":             "DOUBLE",
+	"DOUBLE PRECISION": "DOUBLE",
+	"FLOAT4":           "FLOAT",
+	"FLOAT8":           "DOUBLE",
+}
+
+// canonicalMySQLType replaces the synonym of the data type in name with the canonical one.
+// (e.g. "INTEGER UNSIGNED" to "INT UNSIGNED", "NUMERIC(10,2)" to "DECIMAL(10,2)")
+func canonicalMySQLType(name string) string {
+	upper := strings.ToUpper(name)
+	for synonym, typ := range mysqlTypeSynonyms {
+		if !strings.HasPrefix(upper, synonym) {
+			continue
+		}
+		rest := name[len(synonym):]
+		if rest == "" || rest[0] == '(' || rest[0] == ' ' {
+			if strings.HasSuffix(typ, ")") && strings.HasPrefix(rest, "(") {
+				continue
+			}
+			return typ + rest
+		}
+	}
+	return name
+}
+
 func (d *MySQL) defaultColumnType(name string) string {
 	switch name := strings.ToUpper(name); name {
 	case "BIT":
@@ -366,7 +1111,7 @@ func (d *MySQL) currentDBName() (string, error) {
 	if d.dbName != "" {
 		return d.dbName, nil
 	}
-	err := d.db.QueryRow(`SELECT DATABASE()`).Scan(&d.dbName)
+	err := d.conn.QueryRowContext(context.Background(), `SELECT DATABASE()`).Scan(&d.dbName)
 	return d.dbName, err
 }
 
@@ -375,7 +1120,7 @@ func (d *MySQL) dbVersion() (*mysqlVersion, error) {
 		return d.version, nil
 	}
 	var version string
-	if err := d.db.QueryRow(`SELECT VERSION()`).Scan(&version); err != nil {
+	if err := d.conn.QueryRowContext(context.Background(), `SELECT VERSION()`).Scan(&version); err != nil {
 		return nil, err
 	}
 	vs := strings.Split(version, "-")
@@ -399,46 +1144,15 @@ func (d *MySQL) dbVersion() (*mysqlVersion, error) {
 	return d.version, err
 }
 
-func (d *MySQL) getIndexMap() (map[string]map[string]mysqlIndexInfo, error) {
-	dbname, err := d.currentDBName()
-	if err != nil {
-		return nil, err
-	}
-	query := strings.Join([]string{
-		"SELECT",
-		"  TABLE_NAME,",
-		"  COLUMN_NAME,",
-		"  NON_UNIQUE,",
-		"  INDEX_NAME",
-		"FROM information_schema.STATISTICS",
-		"WHERE TABLE_SCHEMA = ?",
-	}, "\n")
-	rows, err := d.db.Query(query, dbname)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	indexMap := make(map[string]map[string]mysqlIndexInfo)
-	for rows.Next() {
-		var (
-			tableName  string
-			columnName string
-			index      mysqlIndexInfo
-		)
-		if err := rows.Scan(&tableName, &columnName, &index.NonUnique, &index.IndexName); err != nil {
-			return nil, err
-		}
-		if _, exists := indexMap[tableName]; !exists {
-			indexMap[tableName] = make(map[string]mysqlIndexInfo)
-		}
-		indexMap[tableName][columnName] = index
-	}
-	return indexMap, rows.Err()
+// mysqlConnector is implemented by the DB that can return a single connection such as *sql.DB.
+type mysqlConnector interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
 }
 
 type mysqlIndexInfo struct {
-	NonUnique int64
-	IndexName string
+	NonUnique  int64
+	IndexName  string
+	SeqInIndex int64
 }
 
 type mysqlVersion struct {
@@ -448,6 +1162,8 @@ type mysqlVersion struct {
 	Name  string
 }
 
+var _ RowsAffectedExecer = &mysqlTransaction{}
+
 type mysqlTransaction struct {
 	tx *sql.Tx
 }
@@ -457,6 +1173,14 @@ func (m *mysqlTransaction) Exec(sql string, args ...interface{}) error {
 	return err
 }
 
+func (m *mysqlTransaction) ExecRowsAffected(sql string, args ...interface{}) (int64, error) {
+	result, err := m.tx.Exec(sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (m *mysqlTransaction) Commit() error {
 	return m.tx.Commit()
 }
@@ -484,7 +1208,11 @@ func trimParens(s string) string {
 	return s[:start] + s[end+1:]
 }
 
-var _ ColumnSchema = &mysqlColumnSchema{}
+var (
+	_ ColumnSchema           = &mysqlColumnSchema{}
+	_ MultiIndexColumnSchema = &mysqlColumnSchema{}
+	_ ForeignKeyColumnSchema = &mysqlColumnSchema{}
+)
 
 type mysqlColumnSchema struct {
 	tableName              string
@@ -502,8 +1230,8 @@ type mysqlColumnSchema struct {
 	columnKey              string
 	extra                  string
 	columnComment          string
-	nonUnique              int64
-	indexName              string
+	indexes                []mysqlIndexInfo
+	foreignKey             string
 
 	version *mysqlVersion
 }
@@ -535,7 +1263,15 @@ func (schema *mysqlColumnSchema) DataType() string {
 }
 
 func (schema *mysqlColumnSchema) IsPrimaryKey() bool {
-	return schema.columnKey == "PRI" && strings.ToUpper(schema.indexName) == "PRIMARY"
+	if schema.columnKey != "PRI" {
+		return false
+	}
+	for _, index := range schema.indexes {
+		if strings.ToUpper(index.IndexName) == "PRIMARY" {
+			return true
+		}
+	}
+	return false
 }
 
 func (schema *mysqlColumnSchema) IsAutoIncrement() bool {
@@ -543,12 +1279,31 @@ func (schema *mysqlColumnSchema) IsAutoIncrement() bool {
 }
 
 func (schema *mysqlColumnSchema) Index() (name string, unique bool, ok bool) {
-	if schema.indexName != "" && !schema.IsPrimaryKey() {
-		return schema.indexName, schema.nonUnique == 0, true
+	if indexes := schema.Indexes(); len(indexes) > 0 {
+		return indexes[0].Name, indexes[0].Unique, true
 	}
 	return "", false, false
 }
 
+func (schema *mysqlColumnSchema) Indexes() []ColumnIndex {
+	var indexes []ColumnIndex
+	for _, index := range schema.indexes {
+		if strings.ToUpper(index.IndexName) == "PRIMARY" {
+			continue
+		}
+		indexes = append(indexes, ColumnIndex{
+			Name:     index.IndexName,
+			Unique:   index.NonUnique == 0,
+			Position: int(index.SeqInIndex),
+		})
+	}
+	return indexes
+}
+
+func (schema *mysqlColumnSchema) ForeignKey() (string, bool) {
+	return schema.foreignKey, schema.foreignKey != ""
+}
+
 func (schema *mysqlColumnSchema) Default() (string, bool) {
 	if !schema.columnDefault.Valid {
 		return "", false
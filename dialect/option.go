@@ -5,6 +5,8 @@ type Option func(*option)
 
 type option struct {
 	columnTypes []*ColumnType
+	ifExists    bool
+	database    string
 }
 
 func newOption() *option {
@@ -17,3 +19,22 @@ func WithColumnType(columnTypes []*ColumnType) Option {
 		o.columnTypes = columnTypes
 	}
 }
+
+// WithIfExists makes the dialect generate CREATE TABLE/INDEX with IF NOT EXISTS and DROP TABLE/INDEX with IF EXISTS,
+// so that the generated SQLs can be applied repeatedly.
+// Note that MySQL does not support IF [NOT] EXISTS for indexes, so the index statements are guarded only on MariaDB.
+func WithIfExists() Option {
+	return func(o *option) {
+		o.ifExists = true
+	}
+}
+
+// WithDatabase makes the dialect inspect the database that has name instead of the current database of the connection,
+// and qualify the table names in the generated SQLs by name, so the connection need not have the default database.
+// It is ignored by Spanner since the database is given by NewSpanner.
+// To target the multiple databases, create the dialect for each database.
+func WithDatabase(name string) Option {
+	return func(o *option) {
+		o.database = name
+	}
+}
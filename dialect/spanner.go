@@ -12,6 +12,15 @@ import (
 	apioption "google.golang.org/api/option"
 	databasepb "google.golang.org/genproto/googleapis/spanner/admin/database/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	_ HistoryStore        = &Spanner{}
+	_ Retryable           = &Spanner{}
+	_ RowCounter          = &Spanner{}
+	_ IdentifierValidator = &Spanner{}
+	_ Placeholderer       = &Spanner{}
 )
 
 var (
@@ -224,6 +233,36 @@ func (s *Spanner) ImportPackage(schema ColumnSchema) string {
 	return ""
 }
 
+// spannerReservedWords is the reserved keywords of Cloud Spanner.
+// See https://cloud.google.com/spanner/docs/reference/standard-sql/lexical#reserved_keywords
+var spannerReservedWords = map[string]struct{}{
+	"ALL": {}, "AND": {}, "ANY": {}, "ARRAY": {}, "AS": {}, "ASC": {}, "ASSERT_ROWS_MODIFIED": {},
+	"AT": {}, "BETWEEN": {}, "BY": {}, "CASE": {}, "CAST": {}, "COLLATE": {}, "CONTAINS": {},
+	"CREATE": {}, "CROSS": {}, "CUBE": {}, "CURRENT": {}, "DEFAULT": {}, "DEFINE": {}, "DESC": {},
+	"DISTINCT": {}, "ELSE": {}, "END": {}, "ENUM": {}, "ESCAPE": {}, "EXCEPT": {}, "EXCLUDE": {},
+	"EXISTS": {}, "EXTRACT": {}, "FALSE": {}, "FETCH": {}, "FOLLOWING": {}, "FOR": {}, "FROM": {},
+	"FULL": {}, "GROUP": {}, "GROUPING": {}, "GROUPS": {}, "HASH": {}, "HAVING": {}, "IF": {},
+	"IGNORE": {}, "IN": {}, "INNER": {}, "INTERSECT": {}, "INTERVAL": {}, "INTO": {}, "IS": {},
+	"JOIN": {}, "LATERAL": {}, "LEFT": {}, "LIKE": {}, "LIMIT": {}, "LOOKUP": {}, "MERGE": {},
+	"NATURAL": {}, "NEW": {}, "NO": {}, "NOT": {}, "NULL": {}, "NULLS": {}, "OF": {}, "ON": {},
+	"OR": {}, "ORDER": {}, "OUTER": {}, "OVER": {}, "PARTITION": {}, "PRECEDING": {}, "PROTO": {},
+	"RANGE": {}, "RECURSIVE": {}, "RESPECT": {}, "RIGHT": {}, "ROLLUP": {}, "ROWS": {}, "SELECT": {},
+	"SET": {}, "SOME": {}, "STRUCT": {}, "TABLESAMPLE": {}, "THEN": {}, "TO": {}, "TREAT": {},
+	"TRUE": {}, "UNBOUNDED": {}, "UNION": {}, "UNNEST": {}, "USING": {}, "WHEN": {}, "WHERE": {},
+	"WINDOW": {}, "WITH": {}, "WITHIN": {},
+}
+
+// MaxIdentifierLength returns the maximum length of the identifiers of Cloud Spanner.
+func (d *Spanner) MaxIdentifierLength() int {
+	return 128
+}
+
+// IsReservedWord reports whether name is a reserved keyword of Cloud Spanner.
+func (d *Spanner) IsReservedWord(name string) bool {
+	_, ok := spannerReservedWords[strings.ToUpper(name)]
+	return ok
+}
+
 func (d *Spanner) Quote(s string) string {
 	return fmt.Sprintf("`%s`", strings.Replace(s, "`", "``", -1))
 }
@@ -232,6 +271,11 @@ func (d *Spanner) QuoteString(s string) string {
 	return fmt.Sprintf("'%s'", strings.Replace(s, "'", `\'`, -1))
 }
 
+// Placeholder returns the positional parameter of Cloud Spanner such as "@p1".
+func (d *Spanner) Placeholder(i int) string {
+	return fmt.Sprintf("@p%d", i)
+}
+
 func (d *Spanner) CreateTableSQL(table Table) []string {
 	columns := make([]string, len(table.Fields))
 	for i, f := range table.Fields {
@@ -248,12 +292,16 @@ func (d *Spanner) CreateTableSQL(table Table) []string {
 		pks[i] = d.Quote(pk)
 	}
 	return []string{
-		fmt.Sprintf("CREATE TABLE %s (\n"+
+		fmt.Sprintf("CREATE TABLE %s%s (\n"+
 			"  %s\n"+
-			") PRIMARY KEY (%s)", d.Quote(table.Name), strings.Join(columns, ",\n  "), strings.Join(pks, ", ")),
+			") PRIMARY KEY (%s)", d.ifNotExists(), d.Quote(table.Name), strings.Join(columns, ",\n  "), strings.Join(pks, ", ")),
 	}
 }
 
+func (d *Spanner) DropTableSQL(table Table) []string {
+	return []string{fmt.Sprintf("DROP TABLE %s%s", d.ifExists(), d.Quote(table.Name))}
+}
+
 func (d *Spanner) AddColumnSQL(field Field) []string {
 	tableName := d.Quote(field.Table)
 	ret := []string{
@@ -299,13 +347,27 @@ func (d *Spanner) CreateIndexSQL(index Index) []string {
 	tableName := d.Quote(index.Table)
 	column := strings.Join(columns, ",")
 	if index.Unique {
-		return []string{fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)", indexName, tableName, column)}
+		return []string{fmt.Sprintf("CREATE UNIQUE INDEX %s%s ON %s (%s)", d.ifNotExists(), indexName, tableName, column)}
 	}
-	return []string{fmt.Sprintf("CREATE INDEX %s ON %s (%s)", indexName, tableName, column)}
+	return []string{fmt.Sprintf("CREATE INDEX %s%s ON %s (%s)", d.ifNotExists(), indexName, tableName, column)}
 }
 
 func (d *Spanner) DropIndexSQL(index Index) []string {
-	return []string{fmt.Sprintf("DROP INDEX %s", d.Quote(index.Name))}
+	return []string{fmt.Sprintf("DROP INDEX %s%s", d.ifExists(), d.Quote(index.Name))}
+}
+
+func (d *Spanner) ifExists() string {
+	if d.opt.ifExists {
+		return "IF EXISTS "
+	}
+	return ""
+}
+
+func (d *Spanner) ifNotExists() string {
+	if d.opt.ifExists {
+		return "IF NOT EXISTS "
+	}
+	return ""
 }
 
 func (d *Spanner) columnSQL(f Field) string {
@@ -318,6 +380,106 @@ func (d *Spanner) Begin() (Transactioner, error) {
 	}, nil
 }
 
+// IsRetryable returns true if err is an aborted or unavailable error.
+func (d *Spanner) IsRetryable(err error) bool {
+	switch spanner.ErrCode(err) {
+	case codes.Aborted, codes.Unavailable:
+		return true
+	}
+	return false
+}
+
+func (d *Spanner) EnsureHistoryTable(table string) error {
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	var count int64
+	if err := client.Single().Query(ctx, spanner.Statement{
+		SQL: "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = '' AND table_name = @table",
+		Params: map[string]interface{}{
+			"table": table,
+		},
+	}).Do(func(row *spanner.Row) error {
+		return row.Column(0, &count)
+	}); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	ac, err := d.adminClient()
+	if err != nil {
+		return err
+	}
+	op, err := ac.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database: d.database,
+		Statements: []string{
+			fmt.Sprintf("CREATE TABLE %s (\n"+
+				"  `checksum` STRING(64) NOT NULL,\n"+
+				"  `statement` STRING(MAX) NOT NULL,\n"+
+				"  `applied_at` TIMESTAMP NOT NULL,\n"+
+				"  `duration_ms` INT64 NOT NULL\n"+
+				") PRIMARY KEY (`checksum`)", d.Quote(table)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
+
+func (d *Spanner) AppliedChecksums(table string) (map[string]struct{}, error) {
+	client, err := d.client()
+	if err != nil {
+		return nil, err
+	}
+	checksums := map[string]struct{}{}
+	if err := client.Single().Query(context.Background(), spanner.Statement{
+		SQL: fmt.Sprintf("SELECT `checksum` FROM %s", d.Quote(table)),
+	}).Do(func(row *spanner.Row) error {
+		var checksum string
+		if err := row.Column(0, &checksum); err != nil {
+			return err
+		}
+		checksums[checksum] = struct{}{}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+func (d *Spanner) CountRows(query string) (int64, error) {
+	client, err := d.client()
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if err := client.Single().Query(context.Background(), spanner.Statement{
+		SQL: query,
+	}).Do(func(row *spanner.Row) error {
+		return row.Column(0, &n)
+	}); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (d *Spanner) RecordHistory(table string, history History) error {
+	client, err := d.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.Apply(context.Background(), []*spanner.Mutation{
+		spanner.InsertOrUpdate(table,
+			[]string{"checksum", "statement", "applied_at", "duration_ms"},
+			[]interface{}{history.Checksum, history.SQL, history.AppliedAt, int64(history.Duration / time.Millisecond)}),
+	})
+	return err
+}
+
 func (d *Spanner) client() (*spanner.Client, error) {
 	if d.c != nil {
 		return d.c, nil
@@ -0,0 +1,138 @@
+package migu
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
+)
+
+// enumType is the named string type of Go for the ENUM column.
+type enumType struct {
+	Name    string
+	Members []string
+}
+
+// enumTypeOf returns the named type for the column if it is an ENUM column.
+// It returns nil if the column is not an ENUM column.
+func (o *option) enumTypeOf(table string, schema dialect.ColumnSchema) *enumType {
+	if !strings.EqualFold(schema.DataType(), "enum") {
+		return nil
+	}
+	members, ok := enumMembers(schema.ColumnType())
+	if !ok {
+		return nil
+	}
+	return &enumType{
+		Name:    o.structName(table) + stringutil.ToUpperCamelCase(schema.ColumnName()),
+		Members: members,
+	}
+}
+
+// enumTypes returns the named types for the ENUM columns of the table.
+func (o *option) enumTypes(table string, schemas []dialect.ColumnSchema) []*enumType {
+	var enums []*enumType
+	for _, schema := range schemas {
+		if e := o.enumTypeOf(table, schema); e != nil {
+			enums = append(enums, e)
+		}
+	}
+	return enums
+}
+
+// enumMembers returns the members of the ENUM column type such as "enum('active','inactive')".
+func enumMembers(columnType string) ([]string, bool) {
+	start, end := strings.IndexByte(columnType, '('), strings.LastIndexByte(columnType, ')')
+	if start < 0 || end < start {
+		return nil, false
+	}
+	s := columnType[start+1 : end]
+	var members []string
+	for len(s) > 0 {
+		if s[0] != '\'' {
+			return nil, false
+		}
+		var b strings.Builder
+		i := 1
+		for ; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			b.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, false
+		}
+		members = append(members, b.String())
+		s = strings.TrimPrefix(strings.TrimLeft(s[i+1:], " "), ",")
+	}
+	return members, true
+}
+
+// setEnumType replaces the string type of f with the named type of the ENUM column.
+func setEnumType(f *ast.Field, e *enumType) {
+	ident, ok := f.Type.(*ast.Ident)
+	if !ok {
+		return
+	}
+	switch ident.Name {
+	case "string", "*string", "sql.Null[string]":
+		f.Type = ast.NewIdent(strings.Replace(ident.Name, "string", e.Name, 1))
+	}
+}
+
+// constName returns the name of the constant for the member.
+func (e *enumType) constName(member string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x80 && isWordByte(byte(r)) {
+			return r
+		}
+		return '_'
+	}, member)
+	name = stringutil.ToUpperCamelCase(strings.ToLower(name))
+	if name == "" {
+		name = "Empty"
+	}
+	return e.Name + name
+}
+
+// enumDeclsAST returns the declarations of the named type and the constants of its members.
+// (e.g. type UserStatus string; const UserStatusActive UserStatus = "active")
+func enumDeclsAST(e *enumType) []ast.Decl {
+	typeDecl := &ast.GenDecl{
+		Tok: token.TYPE,
+		Specs: []ast.Spec{
+			&ast.TypeSpec{
+				Name: ast.NewIdent(e.Name),
+				Type: ast.NewIdent("string"),
+			},
+		},
+	}
+	constDecl := &ast.GenDecl{
+		Tok:    token.CONST,
+		Lparen: 1,
+	}
+	for _, member := range e.Members {
+		constDecl.Specs = append(constDecl.Specs, &ast.ValueSpec{
+			Names: []*ast.Ident{
+				ast.NewIdent(e.constName(member)),
+			},
+			Type: ast.NewIdent(e.Name),
+			Values: []ast.Expr{
+				&ast.BasicLit{
+					Kind:  token.STRING,
+					Value: strconv.Quote(member),
+				},
+			},
+		})
+	}
+	return []ast.Decl{typeDecl, constDecl}
+}
@@ -0,0 +1,145 @@
+package migu
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteERDiagram writes the ER diagram of schema to w in format.
+// The supported formats are "mermaid" and "dot" for Graphviz.
+// schema is typically the schema that is returned by ParseSchema or InspectSchema.
+//
+// The relationships are drawn from the columns that refer to the other tables by `fk` tag.
+// Note that the schema returned by InspectSchema has no relationships.
+func WriteERDiagram(w io.Writer, schema *Schema, format string) error {
+	switch format {
+	case "mermaid":
+		return writeMermaid(w, schema)
+	case "dot":
+		return writeDOT(w, schema)
+	}
+	return fmt.Errorf("migu: unsupported ER diagram format: %s", format)
+}
+
+// relationship is the reference from the column of the table to the column of the other table.
+type relationship struct {
+	table     *Table
+	column    *Column
+	refTable  string
+	refColumn string
+}
+
+func relationships(schema *Schema) []*relationship {
+	var rels []*relationship
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			if c.References == "" {
+				continue
+			}
+			table, column := splitReference(c.References)
+			rels = append(rels, &relationship{
+				table:     t,
+				column:    c,
+				refTable:  table,
+				refColumn: column,
+			})
+		}
+	}
+	return rels
+}
+
+// splitReference splits the reference such as "user.id" into the table name and the column name.
+func splitReference(ref string) (table, column string) {
+	i := strings.LastIndexByte(ref, '.')
+	return ref[:i], ref[i+1:]
+}
+
+// columnKeys returns the key markers such as "PK" and "FK" of the column.
+func columnKeys(t *Table, c *Column) []string {
+	var keys []string
+	if c.PrimaryKey {
+		keys = append(keys, "PK")
+	}
+	if c.References != "" {
+		keys = append(keys, "FK")
+	}
+	for _, index := range t.Indexes {
+		if !index.Unique {
+			continue
+		}
+		if inStrings(index.Columns, c.Name) {
+			keys = append(keys, "UK")
+			break
+		}
+	}
+	return keys
+}
+
+func writeMermaid(w io.Writer, schema *Schema) error {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, t := range schema.Tables {
+		fmt.Fprintf(&b, "    %s {\n", mermaidName(t.Name))
+		for _, c := range t.Columns {
+			fmt.Fprintf(&b, "        %s %s", mermaidName(c.Type), mermaidName(c.Name))
+			if keys := columnKeys(t, c); len(keys) > 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(keys, ", "))
+			}
+			if c.Comment != "" {
+				fmt.Fprintf(&b, " %q", strings.Replace(c.Comment, `"`, "'", -1))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, rel := range relationships(schema) {
+		parent := "||"
+		if rel.column.Nullable {
+			parent = "|o"
+		}
+		fmt.Fprintf(&b, "    %s %s--o{ %s : %q\n", mermaidName(rel.refTable), parent, mermaidName(rel.table.Name), rel.column.Name)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidName replaces the characters that cannot be used in the names of Mermaid's ER diagram with underscores.
+func mermaidName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x80 && isWordByte(byte(r)) || r == '-' || r == '(' || r == ')' || r == '[' || r == ']' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func writeDOT(w io.Writer, schema *Schema) error {
+	var b strings.Builder
+	b.WriteString("digraph schema {\n")
+	b.WriteString("\tnode [shape=plaintext];\n")
+	for _, t := range schema.Tables {
+		fmt.Fprintf(&b, "\t%s [label=<\n", strconv.Quote(t.Name))
+		b.WriteString("\t\t<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">\n")
+		fmt.Fprintf(&b, "\t\t<tr><td colspan=\"2\" bgcolor=\"lightgray\"><b>%s</b></td></tr>\n", html.EscapeString(t.Name))
+		for _, c := range t.Columns {
+			name := html.EscapeString(c.Name)
+			if keys := columnKeys(t, c); len(keys) > 0 {
+				name += " (" + strings.Join(keys, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "\t\t<tr><td port=%s align=\"left\">%s</td><td align=\"left\">%s</td></tr>\n",
+				strconv.Quote(html.EscapeString(c.Name)), name, html.EscapeString(c.Type))
+		}
+		b.WriteString("\t\t</table>\n")
+		b.WriteString("\t>];\n")
+	}
+	for _, rel := range relationships(schema) {
+		fmt.Fprintf(&b, "\t%s:%s -> %s:%s;\n",
+			strconv.Quote(rel.table.Name), strconv.Quote(rel.column.Name), strconv.Quote(rel.refTable), strconv.Quote(rel.refColumn))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
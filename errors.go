@@ -0,0 +1,30 @@
+package migu
+
+import "fmt"
+
+// ExecError is the error returned by Sync when a statement of the migration fails.
+type ExecError struct {
+	// Operation is the operation that failed.
+	Operation Operation
+
+	// Index is the index of the failed operation in the migration plan.
+	Index int
+
+	// Applied is the operations that had been executed successfully before the failed operation.
+	Applied []Operation
+
+	// Err is the underlying error returned from the database driver.
+	Err error
+}
+
+func (e *ExecError) Error() string {
+	target := e.Operation.Table
+	if e.Operation.Column != "" {
+		target += "." + e.Operation.Column
+	}
+	return fmt.Sprintf("migu: failed to execute statement #%d (%s on %s): %v\n%s", e.Index, e.Operation.Kind, target, e.Err, e.Operation.SQL)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
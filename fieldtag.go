@@ -0,0 +1,91 @@
+package migu
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+	"unicode"
+
+	"github.com/naoina/go-stringutil"
+)
+
+// NamingStyle represents the naming style of the names in the struct tags generated by Fprint.
+type NamingStyle int
+
+const (
+	// NamingSnakeCase uses the column name as is. (e.g. "user_id")
+	NamingSnakeCase NamingStyle = iota
+
+	// NamingLowerCamelCase converts the column name to lower camel case. (e.g. "userID")
+	NamingLowerCamelCase
+
+	// NamingUpperCamelCase converts the column name to upper camel case. (e.g. "UserID")
+	NamingUpperCamelCase
+)
+
+func (s NamingStyle) String() string {
+	switch s {
+	case NamingSnakeCase:
+		return "snake"
+	case NamingLowerCamelCase:
+		return "lower-camel"
+	case NamingUpperCamelCase:
+		return "upper-camel"
+	}
+	return fmt.Sprintf("NamingStyle(%d)", int(s))
+}
+
+// ParseNamingStyle returns the NamingStyle from its name such as "snake".
+func ParseNamingStyle(s string) (NamingStyle, error) {
+	for _, style := range []NamingStyle{NamingSnakeCase, NamingLowerCamelCase, NamingUpperCamelCase} {
+		if strings.EqualFold(s, style.String()) {
+			return style, nil
+		}
+	}
+	return NamingSnakeCase, fmt.Errorf("migu: unknown naming style: %s", s)
+}
+
+func (s NamingStyle) name(column string) string {
+	switch s {
+	case NamingLowerCamelCase:
+		return toLowerCamelCase(column)
+	case NamingUpperCamelCase:
+		return stringutil.ToUpperCamelCase(column)
+	}
+	return column
+}
+
+// toLowerCamelCase converts s to lower camel case.
+// The leading initialism is converted to lower case entirely. (e.g. "id_number" to "idNumber")
+func toLowerCamelCase(s string) string {
+	rs := []rune(stringutil.ToUpperCamelCase(s))
+	n := 0
+	for n < len(rs) && unicode.IsUpper(rs[n]) {
+		n++
+	}
+	if n > 1 && n < len(rs) && unicode.IsLower(rs[n]) {
+		n--
+	}
+	for i := 0; i < n; i++ {
+		rs[i] = unicode.ToLower(rs[i])
+	}
+	return string(rs)
+}
+
+// fieldTag is the struct tag that is added to the fields generated by Fprint.
+type fieldTag struct {
+	key   string
+	value func(column string) string
+}
+
+// addFieldTags adds the struct tags of opt to the field for column.
+func (o *option) addFieldTags(f *ast.Field, column string) {
+	if len(o.fieldTags) == 0 || f.Tag == nil {
+		return
+	}
+	tags := make([]string, len(o.fieldTags))
+	for i, tag := range o.fieldTags {
+		tags[i] = fmt.Sprintf("%s:%q", tag.key, tag.value(column))
+	}
+	f.Tag.Value = strings.TrimSuffix(f.Tag.Value, "`") + " " + strings.Join(tags, " ") + "`"
+}
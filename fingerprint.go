@@ -0,0 +1,86 @@
+package migu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// Fingerprint returns the SHA-256 checksum of the schema in hex.
+// The checksum is computed only from the attributes that Diff compares, so the schema
+// returned by ParseSchema and by InspectSchema have the same fingerprint if there are no differences.
+func (s *Schema) Fingerprint() string {
+	tables := make([]*Table, len(s.Tables))
+	copy(tables, s.Tables)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+	h := sha256.New()
+	for _, t := range tables {
+		writeTableFingerprint(h, t)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeTableFingerprint(w io.Writer, t *Table) {
+	fmt.Fprintf(w, "table %q\n", t.Name)
+	for _, c := range t.Columns {
+		fmt.Fprintf(w, "column %q %q null=%v pk=%v autoincrement=%v default=%q extra=%q comment=%q\n",
+			c.Name, normalizeColumnType(c.Type), c.Nullable, c.PrimaryKey, c.AutoIncrement, normalizeDefault(c.Type, c.Default), c.Extra, c.Comment)
+	}
+	indexes := make([]*Index, len(t.Indexes))
+	copy(indexes, t.Indexes)
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	for _, index := range indexes {
+		fmt.Fprintf(w, "index %q unique=%v %q\n", index.Name, index.Unique, index.Columns)
+	}
+}
+
+// DriftError is the error returned by CheckDrift when the database schema differs from Go's structs.
+type DriftError struct {
+	// Expected is the fingerprint of the schema that is defined by Go's structs.
+	Expected string
+
+	// Actual is the fingerprint of the schema on the database.
+	Actual string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("migu: the database schema has drifted from the definition: expected fingerprint %s, but got %s", e.Expected, e.Actual)
+}
+
+// CheckDrift compares the fingerprint of the schema that is defined by Go's structs with that of the database.
+// Go's structs are read in the same way as Diff reads filename and src, and only the tables that are defined by them are inspected.
+// It returns *DriftError if the fingerprints differ.
+func CheckDrift(d dialect.Dialect, filename string, src interface{}, opts ...Option) error {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return err
+	}
+	o := newOption(opts...)
+	structMap, err := makeTableMap(d, structASTMap, o)
+	if err != nil {
+		return err
+	}
+	tableMap, err := inspectTableMap(d, structMap, o)
+	if err != nil {
+		return err
+	}
+	expected, actual := newSchema(structMap).Fingerprint(), newSchema(tableMap).Fingerprint()
+	if o.metrics != nil {
+		o.metrics.ObserveDrift(expected != actual)
+	}
+	if expected != actual {
+		return &DriftError{
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+	return nil
+}
@@ -1,6 +1,6 @@
 module github.com/naoina/migu
 
-go 1.14
+go 1.16
 
 require (
 	cloud.google.com/go/spanner v1.14.1
@@ -11,6 +11,7 @@ require (
 	github.com/naoina/go-stringutil v0.1.0
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	google.golang.org/api v0.40.0
 	google.golang.org/genproto v0.0.0-20210207032614-bba0dbe2a9ea
 	google.golang.org/grpc v1.35.0
@@ -0,0 +1,157 @@
+package migu
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// goDumpHeader is the header of the files written by FprintDir.
+const goDumpHeader = "// Code generated by migu. DO NOT EDIT.\n"
+
+// FprintDir is like Fprint, but writes Go's struct of each table in the database to "<table>.go" file in dir.
+// Each file has the package clause and the imports that are needed by the struct, so it can be compiled as is.
+// If the file name would be ignored or constrained by the go command, such as "foo_test.go" or "bar_linux.go",
+// "_table" is appended to the table name, and "table" is prepended to the table name that begins with "_" or ".".
+// The package name is the base name of dir unless WithPackageName option is given.
+//
+// If WithMerge option is given, the existing files are merged with the database schema instead of being overwritten.
+//
+// As with DumpSQLDir, the files are only rewritten if their contents are changed, and
+// the generated files of the tables that no longer exist in the database are removed.
+func FprintDir(dir string, d dialect.Dialect, opts ...Option) error {
+	o := newOption(opts...)
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return err
+	}
+	if err := o.filterTableMap(tableMap); err != nil {
+		return err
+	}
+	if err := o.filterViews(d, tableMap); err != nil {
+		return err
+	}
+	o.filterUnsupportedTypes(d, tableMap)
+	comments, err := tableComments(d)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	pkg := o.packageName
+	if pkg == "" {
+		if pkg, err = packageName(dir); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(tableMap))
+	for name := range tableMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	filenames := make([]string, len(names))
+	srcs := make([][]byte, len(names))
+	if err := parallelDo(len(names), o.concurrency(), func(i int) (err error) {
+		filenames[i] = filepath.Join(dir, goFileName(names[i]))
+		srcs[i], err = goFileSource(filenames[i], pkg, d, names[i], tableMap, comments, o)
+		return err
+	}); err != nil {
+		return err
+	}
+	written := make(map[string]struct{}, len(names))
+	for i, filename := range filenames {
+		written[filename] = struct{}{}
+		if err := writeFileIfChanged(filename, srcs[i]); err != nil {
+			return err
+		}
+	}
+	return removeStaleFiles(filepath.Join(dir, "*.go"), goDumpHeader, written)
+}
+
+// goFileName returns the name of the Go file of the table that has name.
+// The name is changed so that the go command neither ignores the file nor applies the implicit build constraints.
+func goFileName(name string) string {
+	if strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".") {
+		name = "table" + name
+	}
+	elems := strings.Split(name, "_")
+	n := len(elems)
+	if n > 1 && (elems[n-1] == "test" || knownOS[elems[n-1]] || knownArch[elems[n-1]]) {
+		name += "_table"
+	}
+	return name + ".go"
+}
+
+// knownOS and knownArch are the values of GOOS and GOARCH that are recognized in the file names by the go command.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true,
+		"illumos": true, "ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true,
+		"plan9": true, "solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+		"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true,
+		"s390": true, "s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// goFileSource returns the source of the file of the table that has name.
+// If WithMerge option is given and the file exists, the existing file is merged with the table.
+func goFileSource(filename, pkg string, d dialect.Dialect, name string, tableMap map[string][]dialect.ColumnSchema, comments map[string]string, o *option) ([]byte, error) {
+	if o.merge {
+		if existing, err := ioutil.ReadFile(filename); err == nil {
+			src, err := mergeStructFile(existing, d, name, tableMap[name], o)
+			if err != nil {
+				return nil, fmt.Errorf("migu: %s: %v", filename, err)
+			}
+			return src, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if !o.editable && !o.merge {
+		buf.WriteString(goDumpHeader)
+	}
+	if o.header != "" {
+		buf.WriteString(commentLines(o.header))
+	}
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if err := fprintTables(&buf, d, tableMap, comments, []string{name}, o); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// packageName returns the package name for the directory.
+func packageName(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '_':
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, filepath.Base(abs))
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "model"
+	}
+	return name, nil
+}
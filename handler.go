@@ -0,0 +1,79 @@
+package migu
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// DriftStatus is the response of the handler returned by DriftHandler.
+type DriftStatus struct {
+	// Synced reports whether the database schema matches Go's structs.
+	Synced bool `json:"synced"`
+
+	// Statements is the pending statements to synchronize the database schema with Go's structs.
+	Statements []*PendingStatement `json:"statements"`
+}
+
+// PendingStatement is the statement that is not yet applied to the database.
+type PendingStatement struct {
+	Kind  string `json:"kind"`
+	Table string `json:"table"`
+	SQL   string `json:"sql"`
+}
+
+// DriftHandler returns the http.Handler that computes the diff between the database and Go's structs on each request,
+// and responds the pending statements. Go's structs are read once in the same way as Diff reads filename and src.
+// It is useful as an ops endpoint or a readiness probe that shows whether the schema matches the deployed code.
+//
+// The handler responds with 200 OK if the database schema is synchronized, or 503 Service Unavailable if there are
+// the pending statements. The response is DriftStatus in JSON if the request has "format=json" query or
+// accepts "application/json", or the pending statements in plain text otherwise.
+// The context of the request is passed to the dialect as WithContext option.
+func DriftHandler(d dialect.Dialect, filename string, src interface{}, opts ...Option) (http.Handler, error) {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o := newOption(append(opts, WithContext(r.Context()))...)
+		ops, err := diffOperations(d, structASTMap, o)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status := &DriftStatus{
+			Synced:     len(ops) == 0,
+			Statements: []*PendingStatement{},
+		}
+		for i, sql := range o.operationSQLs(ops) {
+			status.Statements = append(status.Statements, &PendingStatement{
+				Kind:  ops[i].Kind.String(),
+				Table: ops[i].Table,
+				SQL:   sql,
+			})
+		}
+		code := http.StatusOK
+		if !status.Synced {
+			code = http.StatusServiceUnavailable
+		}
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(code)
+		if status.Synced {
+			fmt.Fprintln(w, "OK")
+			return
+		}
+		for _, s := range status.Statements {
+			fmt.Fprintf(w, "%s;\n", s.SQL)
+		}
+	}), nil
+}
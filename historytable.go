@@ -0,0 +1,97 @@
+package migu
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// The suffix of the name of the history table and the names of its metadata columns.
+const (
+	historyTableSuffix     = "_history"
+	historyIDColumn        = "history_id"
+	historyActionColumn    = "history_action"
+	historyChangedAtColumn = "history_changed_at"
+)
+
+// addHistoryTables adds the history tables of the tables of structMap that have "history" annotation.
+// The history table named "<table>_history" has the same columns as the table without the keys and the indexes,
+// and the metadata columns of the changes: history_id is the primary key, history_action is the kind of the change
+// such as "insert", "update" and "delete", and history_changed_at is the time of the change.
+// The rows of the history table are written by the application or the triggers, not by migu.
+func addHistoryTables(d dialect.Dialect, structMap map[string]*table) error {
+	var names []string
+	for name, tbl := range structMap {
+		if tbl.History {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		historyName := name + historyTableSuffix
+		if _, exists := structMap[historyName]; exists {
+			return fmt.Errorf("migu: history table `%s' of `%s' is already defined", historyName, name)
+		}
+		tbl, err := makeHistoryTable(d, historyName, structMap[name])
+		if err != nil {
+			return err
+		}
+		structMap[historyName] = tbl
+	}
+	return nil
+}
+
+// makeHistoryTable returns the history table named name of tbl.
+func makeHistoryTable(d dialect.Dialect, name string, tbl *table) (*table, error) {
+	history := &table{
+		Options:  tbl.Options,
+		ReadOnly: tbl.ReadOnly,
+		Fields:   make([]*field, 0, len(tbl.Fields)+3),
+		Fset:     tbl.Fset,
+		Pos:      tbl.Pos,
+	}
+	history.Fields = append(history.Fields, &field{
+		Table:         name,
+		Name:          "HistoryID",
+		GoType:        "uint64",
+		Type:          d.ColumnType("uint64"),
+		Column:        historyIDColumn,
+		PrimaryKey:    true,
+		AutoIncrement: true,
+	})
+	for _, f := range tbl.Fields {
+		switch f.Column {
+		case historyIDColumn, historyActionColumn, historyChangedAtColumn:
+			return nil, fmt.Errorf("migu: column `%s' of `%s' conflicts with the column of the history table", f.Column, f.Table)
+		}
+		// The history table has many rows for a row of the table, so that the keys and the indexes are not copied.
+		// The extra attributes such as "ON UPDATE CURRENT_TIMESTAMP" are also dropped to keep the values as they were.
+		history.Fields = append(history.Fields, &field{
+			Table:    name,
+			Name:     f.Name,
+			GoType:   f.GoType,
+			Type:     f.Type,
+			Column:   f.Column,
+			Comment:  f.Comment,
+			Default:  f.Default,
+			Nullable: f.Nullable,
+			Pos:      f.Pos,
+			HasType:  f.HasType,
+		})
+	}
+	history.Fields = append(history.Fields, &field{
+		Table:  name,
+		Name:   "HistoryAction",
+		GoType: "string",
+		Type:   d.ColumnType("string"),
+		Column: historyActionColumn,
+	}, &field{
+		Table:  name,
+		Name:   "HistoryChangedAt",
+		GoType: "time.Time",
+		Type:   d.ColumnType("time.Time"),
+		Column: historyChangedAtColumn,
+	})
+	return history, nil
+}
@@ -0,0 +1,66 @@
+package migu
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// validateIdentifiers returns ValidationErrors if the names of the tables, the columns and the indexes of structMap
+// are longer than the limit of the dialect or are reserved words.
+// It returns nil unless WithIdentifierValidation option is given and d implements dialect.IdentifierValidator.
+func (o *option) validateIdentifiers(d dialect.Dialect, structMap map[string]*table) error {
+	if !o.identifierValidation {
+		return nil
+	}
+	iv, ok := d.(dialect.IdentifierValidator)
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(structMap))
+	for name := range structMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs ValidationErrors
+	validate := func(tbl *table, pos token.Pos, kind, name string) {
+		var msg string
+		switch {
+		case len(name) > iv.MaxIdentifierLength():
+			msg = fmt.Sprintf("%s name `%s' is longer than %d characters", kind, name, iv.MaxIdentifierLength())
+		case iv.IsReservedWord(name):
+			msg = fmt.Sprintf("%s name `%s' is a reserved word", kind, name)
+		default:
+			return
+		}
+		var position token.Position
+		if tbl.Fset != nil {
+			position = tbl.Fset.Position(pos)
+		}
+		errs = append(errs, &ValidationError{
+			Pos:     position,
+			Message: msg,
+		})
+	}
+	for _, name := range names {
+		tbl := structMap[name]
+		validate(tbl, tbl.Pos, "table", name)
+		indexes := map[string]struct{}{}
+		for _, f := range tbl.Fields {
+			validate(tbl, f.Pos, "column", f.Column)
+			for _, index := range append(f.Indexes(), f.UniqueIndexes()...) {
+				if _, exists := indexes[index]; exists {
+					continue
+				}
+				indexes[index] = struct{}{}
+				validate(tbl, f.Pos, "index", index)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
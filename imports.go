@@ -0,0 +1,164 @@
+package migu
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// importPaths maps the package names to the import paths of the packages that are used by the generated field types.
+var importPaths = map[string]string{
+	"big":     "math/big",
+	"civil":   "cloud.google.com/go/civil",
+	"decimal": "github.com/shopspring/decimal",
+	"gorp":    "github.com/go-gorp/gorp",
+	"json":    "encoding/json",
+	"mysql":   "github.com/go-sql-driver/mysql",
+	"spanner": "cloud.google.com/go/spanner",
+	"sql":     "database/sql",
+	"time":    "time",
+	"uuid":    "github.com/google/uuid",
+}
+
+var qualifierRegexp = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.`)
+
+// importPackages returns the sorted packages that are needed by Go's structs of the tables that have names.
+// The packages given by WithImports are also candidates.
+// Only the packages that are referenced by src, which is the generated declarations, are returned
+// so that the unused packages are not imported even if the structs are replaced by WithTemplate.
+func importPackages(d dialect.Dialect, tableMap map[string][]dialect.ColumnSchema, names []string, src []byte, o *option) ([]string, error) {
+	pkgMap := map[string]struct{}{}
+	for _, pkg := range o.imports {
+		pkgMap[pkg] = struct{}{}
+	}
+	for _, name := range names {
+		if o.crud && o.template == nil && !o.isView(name) {
+			for _, pkg := range crudImports {
+				pkgMap[pkg] = struct{}{}
+			}
+		}
+		schemas := tableMap[name]
+		decl, err := makeStructAST(d, name, schemas, o)
+		if err != nil {
+			return nil, err
+		}
+		fields := decl.(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.StructType).Fields.List
+		for i, f := range fields {
+			var typ bytes.Buffer
+			if err := format.Node(&typ, token.NewFileSet(), f.Type); err != nil {
+				return nil, err
+			}
+			for _, m := range qualifierRegexp.FindAllStringSubmatch(typ.String(), -1) {
+				if pkg := importPath(d, schemas[i], m[1]); pkg != "" {
+					pkgMap[pkg] = struct{}{}
+				}
+			}
+		}
+	}
+	referenced := referencedNames(src)
+	pkgs := make([]string, 0, len(pkgMap))
+	for pkg := range pkgMap {
+		if _, ok := referenced[importName(pkg)]; ok {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Strings(pkgs)
+	return pkgs, nil
+}
+
+// referencedNames returns the names that are used as the qualifiers in src such as "time" of "time.Time".
+// src is either the Go file or the declarations without the package clause.
+// If src cannot be parsed as Go, the qualifier-like words in src are returned.
+func referencedNames(src []byte) map[string]struct{} {
+	names := map[string]struct{}{}
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		f, err = parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), src...), 0)
+	}
+	if err != nil {
+		for _, m := range qualifierRegexp.FindAllSubmatch(src, -1) {
+			names[string(m[1])] = struct{}{}
+		}
+		return names
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				names[ident.Name] = struct{}{}
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// importName returns the package name that is assumed from the import path in the same way as goimports.
+// e.g. "github.com/go-yaml/yaml/v2" is "yaml", and "gopkg.in/yaml.v2" is "yaml".
+func importName(pkg string) string {
+	name := path.Base(pkg)
+	if strings.HasPrefix(name, "v") {
+		if _, err := strconv.Atoi(name[1:]); err == nil && path.Dir(pkg) != "." {
+			name = path.Base(path.Dir(pkg))
+		}
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// importPath returns the import path of the package that has name.
+// The package that is reported by the dialect takes precedence over the well-known packages.
+// It returns the empty string if the package is unknown.
+func importPath(d dialect.Dialect, schema dialect.ColumnSchema, name string) string {
+	if pkg := d.ImportPackage(schema); pkg != "" && path.Base(pkg) == name {
+		return pkg
+	}
+	return importPaths[name]
+}
+
+// fprintImports writes the import declaration of pkgs as a single block.
+// The standard packages and the other packages are separated by a blank line in the same way as goimports.
+func fprintImports(output io.Writer, pkgs []string) {
+	if len(pkgs) == 0 {
+		return
+	}
+	if len(pkgs) == 1 {
+		fmt.Fprintf(output, "import %q\n\n", pkgs[0])
+		return
+	}
+	var std, others []string
+	for _, pkg := range pkgs {
+		if strings.Contains(strings.SplitN(pkg, "/", 2)[0], ".") {
+			others = append(others, pkg)
+		} else {
+			std = append(std, pkg)
+		}
+	}
+	fmt.Fprintln(output, "import (")
+	for _, pkg := range std {
+		fmt.Fprintf(output, "\t%q\n", pkg)
+	}
+	if len(std) > 0 && len(others) > 0 {
+		fmt.Fprintln(output)
+	}
+	for _, pkg := range others {
+		fmt.Fprintf(output, "\t%q\n", pkg)
+	}
+	fmt.Fprint(output, ")\n\n")
+}
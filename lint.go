@@ -0,0 +1,298 @@
+package migu
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
+)
+
+// Severity represents the severity of a LintIssue.
+type Severity int
+
+const (
+	// SeverityOff disables the lint rule.
+	SeverityOff Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityOff:
+		return "off"
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the Severity from its name such as "warning".
+func ParseSeverity(s string) (Severity, error) {
+	for _, sev := range []Severity{SeverityOff, SeverityInfo, SeverityWarning, SeverityError} {
+		if strings.EqualFold(s, sev.String()) {
+			return sev, nil
+		}
+	}
+	return SeverityOff, fmt.Errorf("migu: unknown severity: %s", s)
+}
+
+// IDs of the lint rules.
+const (
+	LintNoPrimaryKey       = "no-primary-key"
+	LintVarcharWithoutSize = "varchar-without-size"
+	LintNullableBool       = "nullable-bool"
+	LintLongIdentifier     = "long-identifier"
+	LintVarcharTooLong     = "varchar-too-long"
+	LintUnindexedFK        = "unindexed-fk"
+)
+
+// LintRule is a rule of Lint.
+type LintRule struct {
+	ID          string
+	Severity    Severity
+	Description string
+}
+
+// LintRules is the list of the lint rules with the default severity.
+var LintRules = []LintRule{
+	{LintNoPrimaryKey, SeverityWarning, "table has no primary key"},
+	{LintVarcharWithoutSize, SeverityInfo, "VARCHAR type is specified without size, so the default size is used"},
+	{LintNullableBool, SeverityWarning, "boolean column is nullable, so it has three states"},
+	{LintLongIdentifier, SeverityError, "identifier is longer than the limit of the database"},
+	{LintVarcharTooLong, SeverityWarning, "VARCHAR size exceeds the limit of the database, so TEXT type is used instead"},
+	{LintUnindexedFK, SeverityWarning, "column that refers to the other table by fk tag is not indexed"},
+}
+
+// maxIdentifierLength is the maximum length of the identifiers for MySQL.
+const maxIdentifierLength = 64
+
+// LintIssue is a problem of the schema that is found by Lint.
+type LintIssue struct {
+	Rule     string
+	Severity Severity
+	Pos      token.Position
+	Table    string
+	Column   string
+	Message  string
+}
+
+func (i *LintIssue) String() string {
+	return fmt.Sprintf("%v: %s: %s (%s)", i.Pos, i.Severity, i.Message, i.Rule)
+}
+
+// Lint finds the smells of the schema that is defined by Go's structs.
+// Go's structs are read in the same way as Diff reads filename and src.
+//
+// The severity of each rule can be changed by WithLintSeverity.
+// Lint returns the error reported by Validate if Go's structs are invalid.
+func Lint(filename string, src interface{}, opts ...Option) ([]*LintIssue, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(filename, src); err != nil {
+		return nil, err
+	}
+	filenames, err := sourceFilenames(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	l := &linter{
+		fset:       token.NewFileSet(),
+		severities: make(map[string]Severity, len(LintRules)),
+	}
+	for _, rule := range LintRules {
+		l.severities[rule.ID] = rule.Severity
+	}
+	for id, sev := range o.lintSeverities {
+		l.severities[id] = sev
+	}
+	for _, filename := range filenames {
+		f, err := parser.ParseFile(l.fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if err := l.lintFile(f); err != nil {
+			return nil, err
+		}
+	}
+	return l.issues, nil
+}
+
+type linter struct {
+	fset       *token.FileSet
+	severities map[string]Severity
+	issues     []*LintIssue
+}
+
+func (l *linter) report(rule string, pos token.Pos, table, column string, format string, args ...interface{}) {
+	sev := l.severities[rule]
+	if sev == SeverityOff {
+		return
+	}
+	l.issues = append(l.issues, &LintIssue{
+		Rule:     rule,
+		Severity: sev,
+		Pos:      l.fset.Position(pos),
+		Table:    table,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) lintFile(f *ast.File) error {
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			t, ok := s.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			a, err := parseSpecAnnotation(l.fset, d, s)
+			if err != nil {
+				return err
+			}
+			if a == nil || a.View {
+				continue
+			}
+			// The shards have the same columns, so that the struct is linted only once by the last name that is the longest.
+			names := a.tableNames(s.Name.Name)
+			if err := l.lintStruct(names[len(names)-1], a.Options, s, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintStruct(tableName string, options dialect.TableOptions, s *ast.TypeSpec, t *ast.StructType) error {
+	if len(tableName) > maxIdentifierLength {
+		l.report(LintLongIdentifier, s.Pos(), tableName, "", "table name `%s' is longer than %d characters", tableName, maxIdentifierLength)
+	}
+	var (
+		hasPrimaryKey bool
+		fkFields      []*field
+	)
+	// indexed is the columns that lead any index, so that the lookups by the column can use the index.
+	indexed := map[string]struct{}{}
+	// leading is the first field of each index by the positions in the index.
+	leading := map[string]*field{}
+	maxVarcharLength := dialect.MySQLMaxVarcharLength(options)
+	for _, fld := range t.Fields.List {
+		if len(fld.Names) == 0 {
+			continue
+		}
+		typeName, err := detectTypeName(fld)
+		if err != nil {
+			return err
+		}
+		f := &field{
+			Table:  tableName,
+			Name:   fld.Names[0].Name,
+			GoType: typeName,
+		}
+		if fld.Tag != nil {
+			s, err := strconv.Unquote(fld.Tag.Value)
+			if err != nil {
+				return err
+			}
+			if err := parseStructTag(nil, f, reflect.StructTag(s)); err != nil {
+				return err
+			}
+		}
+		if f.Ignore || !(ast.IsExported(f.Name) || (f.Name == "_" && f.Column != "")) {
+			continue
+		}
+		if f.Column == "" {
+			f.Column = stringutil.ToSnakeCase(f.Name)
+		}
+		if f.PrimaryKey {
+			if !hasPrimaryKey {
+				indexed[f.Column] = struct{}{}
+			}
+			hasPrimaryKey = true
+		}
+		for _, index := range append(f.Indexes(), f.UniqueIndexes()...) {
+			if l, ok := leading[index]; !ok || f.IndexPosition(index) < l.IndexPosition(index) {
+				leading[index] = f
+			}
+		}
+		if f.ForeignKey != "" {
+			f.Pos = fld.Pos()
+			fkFields = append(fkFields, f)
+		}
+		if len(f.Column) > maxIdentifierLength {
+			l.report(LintLongIdentifier, fld.Pos(), tableName, f.Column, "column name `%s' is longer than %d characters", f.Column, maxIdentifierLength)
+		}
+		for _, index := range append(f.Indexes(), f.UniqueIndexes()...) {
+			if len(index) > maxIdentifierLength {
+				l.report(LintLongIdentifier, fld.Pos(), tableName, f.Column, "index name `%s' is longer than %d characters", index, maxIdentifierLength)
+			}
+		}
+		switch typ := strings.ToUpper(strings.TrimSpace(f.Type)); typ {
+		case "VARCHAR", "VARBINARY":
+			l.report(LintVarcharWithoutSize, fld.Pos(), tableName, f.Column, "column `%s' has %s type without size", f.Column, typ)
+		}
+		if size, ok := varcharSize(f.Type); ok && size > maxVarcharLength {
+			l.report(LintVarcharTooLong, fld.Pos(), tableName, f.Column, "column `%s' has VARCHAR(%d) type that exceeds %d characters, so it is created as TEXT type", f.Column, size, maxVarcharLength)
+		}
+		if isNullableBool(f) {
+			l.report(LintNullableBool, fld.Pos(), tableName, f.Column, "column `%s' is a nullable boolean", f.Column)
+		}
+	}
+	for _, f := range leading {
+		indexed[f.Column] = struct{}{}
+	}
+	for _, f := range fkFields {
+		if _, ok := indexed[f.Column]; !ok {
+			l.report(LintUnindexedFK, f.Pos, tableName, f.Column, "column `%s' refers to `%s' but is not indexed", f.Column, f.ForeignKey)
+		}
+	}
+	if !hasPrimaryKey {
+		l.report(LintNoPrimaryKey, s.Pos(), tableName, "", "table `%s' has no primary key", tableName)
+	}
+	return nil
+}
+
+// varcharSize returns the size of the VARCHAR type such as "varchar(255)".
+func varcharSize(typ string) (int, bool) {
+	typ = strings.ToUpper(strings.TrimSpace(typ))
+	if !strings.HasPrefix(typ, "VARCHAR(") || !strings.HasSuffix(typ, ")") {
+		return 0, false
+	}
+	size, err := strconv.Atoi(typ[len("VARCHAR(") : len(typ)-1])
+	if err != nil {
+		return 0, false
+	}
+	return size, true
+}
+
+func isNullableBool(f *field) bool {
+	switch f.GoType {
+	case "*bool", "sql.NullBool", "sql.Null[bool]", "spanner.NullBool":
+		return true
+	case "bool":
+		return f.Nullable
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package migu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the level of the log entries of Logger.
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Logger is the interface to log the progress of Sync, Diff and Plan.
+//
+// The introspection and the planning are logged in LogLevelDebug, each executed or skipped statement in LogLevelInfo,
+// and the failed statement in LogLevelError. The methods are called synchronously, so they should return quickly.
+type Logger interface {
+	// Log logs msg in level with attrs.
+	Log(level LogLevel, msg string, attrs ...Attribute)
+}
+
+// The keys of the attributes of the log entries other than the keys of the spans.
+const (
+	AttributeDuration   = "duration"
+	AttributeReason     = "reason"
+	AttributeError      = "error"
+	AttributeOperations = "operations"
+	AttributeTables     = "tables"
+)
+
+// NewTextLogger returns the Logger that writes the entries of level or higher to w in the form of "time level msg key=value ...".
+func NewTextLogger(w io.Writer, level LogLevel) Logger {
+	return &writerLogger{w: w, level: level}
+}
+
+// NewJSONLogger returns the Logger that writes the entries of level or higher to w in JSON lines.
+// Each line has "time", "level" and "msg" fields, and the attributes as the fields.
+func NewJSONLogger(w io.Writer, level LogLevel) Logger {
+	return &writerLogger{w: w, level: level, json: true}
+}
+
+type writerLogger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+	json  bool
+}
+
+func (l *writerLogger) Log(level LogLevel, msg string, attrs ...Attribute) {
+	if level < l.level {
+		return
+	}
+	now := time.Now().Format(time.RFC3339)
+	var line []byte
+	if l.json {
+		// The fields are written in order, so that the output is stable.
+		buf := []byte(`{"time":` + strconv.Quote(now) + `,"level":"` + level.String() + `","msg":`)
+		buf = appendJSONString(buf, msg)
+		for _, attr := range attrs {
+			buf = append(buf, ',')
+			buf = appendJSONString(buf, attr.Key)
+			buf = append(buf, ':')
+			buf = appendJSONString(buf, attr.Value)
+		}
+		line = append(buf, "}\n"...)
+	} else {
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s %-5s %s", now, strings.ToUpper(level.String()), msg)
+		for _, attr := range attrs {
+			value := attr.Value
+			if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+				value = strconv.Quote(value)
+			}
+			fmt.Fprintf(&b, " %s=%s", attr.Key, value)
+		}
+		line = []byte(b.String() + "\n")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(line)
+}
+
+func appendJSONString(buf []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(buf, b...)
+}
+
+// log logs msg by the logger given by WithLogger if any.
+func (o *option) log(level LogLevel, msg string, attrs ...Attribute) {
+	if o.logger != nil {
+		o.logger.Log(level, msg, attrs...)
+	}
+}
+
+// operationAttributes returns the attributes of the log entries of op.
+func operationAttributes(op Operation) []Attribute {
+	return []Attribute{
+		{Key: AttributeOperation, Value: op.Kind.String()},
+		{Key: AttributeTable, Value: op.Table},
+		{Key: AttributeStatement, Value: op.SQL},
+	}
+}
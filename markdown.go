@@ -0,0 +1,117 @@
+package migu
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// markdownDumpHeader is the header of the files written by DumpMarkdownDir.
+const markdownDumpHeader = "<!-- Code generated by migu. DO NOT EDIT. -->\n"
+
+// DumpMarkdownDir writes the document of each table in the database to "<table>.md" file in dir.
+// The document consists of the columns with their types, nullability, defaults and comments, and the indexes.
+//
+// The files are written in the same way as DumpSQLDir.
+func DumpMarkdownDir(d dialect.Dialect, dir string, opts ...Option) error {
+	o := newOption(opts...)
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return err
+	}
+	if err := o.filterTableMap(tableMap); err != nil {
+		return err
+	}
+	m, err := makeTableMapFromColumnSchemas(d, tableMap, o)
+	if err != nil {
+		return err
+	}
+	comments, err := tableComments(d)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	written := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		filename := filepath.Join(dir, name+".md")
+		written[filename] = struct{}{}
+		if err := writeFileIfChanged(filename, tableMarkdown(name, comments[name], m[name])); err != nil {
+			return err
+		}
+	}
+	return removeStaleFiles(filepath.Join(dir, "*.md"), markdownDumpHeader, written)
+}
+
+func tableMarkdown(name, comment string, tbl *table) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(markdownDumpHeader)
+	fmt.Fprintf(&buf, "\n# %s\n", name)
+	if comment != "" {
+		fmt.Fprintf(&buf, "\n%s\n", comment)
+	}
+	buf.WriteString("\n## Columns\n\n")
+	buf.WriteString("| Name | Type | Nullable | Default | Extra | Comment |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- | --- |\n")
+	var pks []string
+	for _, f := range tbl.Fields {
+		var extras []string
+		if f.PrimaryKey {
+			pks = append(pks, f.Column)
+			extras = append(extras, "PRIMARY KEY")
+		}
+		if f.AutoIncrement {
+			extras = append(extras, "AUTO_INCREMENT")
+		}
+		if f.Extra != "" {
+			extras = append(extras, f.Extra)
+		}
+		fmt.Fprintf(&buf, "| `%s` | %s | %s | %s | %s | %s |\n",
+			f.Column, markdownCell(f.Type), yesNo(f.Nullable), markdownCell(f.Default), markdownCell(strings.Join(extras, ", ")), markdownCell(f.Comment))
+	}
+	indexes, _ := makeIndexes(nil, tbl.Fields)
+	if len(pks) == 0 && len(indexes) == 0 {
+		return buf.Bytes()
+	}
+	buf.WriteString("\n## Indexes\n\n")
+	buf.WriteString("| Name | Columns | Unique |\n")
+	buf.WriteString("| --- | --- | --- |\n")
+	if len(pks) > 0 {
+		fmt.Fprintf(&buf, "| PRIMARY | %s | YES |\n", markdownColumns(pks))
+	}
+	for _, index := range indexes {
+		fmt.Fprintf(&buf, "| `%s` | %s | %s |\n", index.Name, markdownColumns(index.Columns), yesNo(index.Unique))
+	}
+	return buf.Bytes()
+}
+
+// markdownCell escapes s to be a cell of the Markdown table.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", "<br>").Replace(s)
+}
+
+func markdownColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "`" + column + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
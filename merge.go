@@ -0,0 +1,366 @@
+package migu
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
+)
+
+// mergeStructFile merges Go's struct of the table into src, and returns the merged source.
+//
+// Only the migu-managed parts of the struct are updated: the fields for the columns, their types and migu tags.
+// The other declarations such as methods, the fields tagged `migu:"-"`, the other struct tags and the comments are preserved.
+// If src has no struct for the table, the struct is appended to src.
+// The header of the generated code is removed, because the merged file is maintained by hand.
+func mergeStructFile(src []byte, d dialect.Dialect, name string, schemas []dialect.ColumnSchema, o *option) ([]byte, error) {
+	src = bytes.TrimPrefix(src, []byte(goDumpHeader))
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	st, err := findStructType(fset, f, name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if st == nil {
+		buf.Write(src)
+		s, err := makeStructAST(d, name, schemas, o)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "\n%s\n", o.annotation(name))
+		if err := fprintln(&buf, s); err != nil {
+			return nil, err
+		}
+	} else {
+		fields, err := mergeFields(fset, src, st, d, schemas, o)
+		if err != nil {
+			return nil, err
+		}
+		open, close := fset.Position(st.Fields.Opening).Offset, fset.Position(st.Fields.Closing).Offset
+		buf.Write(src[:open+1])
+		buf.WriteString("\n")
+		buf.WriteString(fields)
+		buf.Write(src[close:])
+	}
+	declared := declaredTypes(f)
+	for _, e := range o.enumTypes(name, schemas) {
+		if _, ok := declared[e.Name]; ok {
+			continue
+		}
+		buf.WriteString("\n")
+		for _, decl := range enumDeclsAST(e) {
+			if err := fprintln(&buf, decl); err != nil {
+				return nil, err
+			}
+		}
+	}
+	pkgs, err := importPackages(d, map[string][]dialect.ColumnSchema{name: schemas}, []string{name}, buf.Bytes(), o)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := addImports(buf.Bytes(), pkgs)
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(merged)
+}
+
+// declaredTypes returns the names of the types that are declared in f.
+func declaredTypes(f *ast.File) map[string]struct{} {
+	types := map[string]struct{}{}
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			if s, ok := spec.(*ast.TypeSpec); ok {
+				types[s.Name.Name] = struct{}{}
+			}
+		}
+	}
+	return types
+}
+
+// findStructType returns the struct type for the table in f.
+// It returns nil if there is no such struct.
+func findStructType(fset *token.FileSet, f *ast.File, name string) (*ast.StructType, error) {
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			t, ok := s.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			a, err := parseSpecAnnotation(fset, d, s)
+			if err != nil {
+				return nil, err
+			}
+			if a == nil {
+				continue
+			}
+			for _, table := range a.tableNames(s.Name.Name) {
+				if table == name {
+					return t, nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// mergeFields returns the source of the fields of st that are merged with the columns.
+func mergeFields(fset *token.FileSet, src []byte, st *ast.StructType, d dialect.Dialect, schemas []dialect.ColumnSchema, o *option) (string, error) {
+	text := func(from, to token.Pos) string {
+		return string(src[fset.Position(from).Offset:fset.Position(to).Offset])
+	}
+	existing := map[string]*ast.Field{}
+	var preserved []string
+	for _, fld := range st.Fields.List {
+		start := fld.Pos()
+		if fld.Doc != nil {
+			start = fld.Doc.Pos()
+		}
+		end := fld.End()
+		if fld.Comment != nil {
+			end = fld.Comment.End()
+		}
+		if len(fld.Names) == 0 || !ast.IsExported(fld.Names[0].Name) && fld.Names[0].Name != "_" {
+			preserved = append(preserved, text(start, end))
+			continue
+		}
+		f := &field{Name: fld.Names[0].Name}
+		if fld.Tag != nil {
+			tag, err := strconv.Unquote(fld.Tag.Value)
+			if err != nil {
+				return "", err
+			}
+			if err := parseStructTag(nil, f, reflect.StructTag(tag)); err != nil {
+				return "", err
+			}
+		}
+		if f.Ignore {
+			preserved = append(preserved, text(start, end))
+			continue
+		}
+		if f.Column == "" {
+			f.Column = stringutil.ToSnakeCase(f.Name)
+		}
+		existing[f.Column] = fld
+	}
+	var lines []string
+	reordered := reorderedIndexes(schemas)
+	for _, schema := range schemas {
+		nf, err := o.structFieldAST(d, schema, reordered)
+		if err != nil {
+			return "", err
+		}
+		var typ bytes.Buffer
+		if err := format.Node(&typ, token.NewFileSet(), nf.Type); err != nil {
+			return "", err
+		}
+		tag := ""
+		if nf.Tag != nil {
+			tag = nf.Tag.Value
+		}
+		comment := ""
+		if nf.Comment != nil {
+			comment = nf.Comment.List[0].Text
+		}
+		var doc string
+		name := nf.Names[0].Name
+		if ef := existing[schema.ColumnName()]; ef != nil {
+			name = ef.Names[0].Name
+			if stringutil.ToSnakeCase(name) != schema.ColumnName() {
+				// The field of the existing name needs the column option to be mapped to the column.
+				t, err := addColumnOption(tag, schema.ColumnName())
+				if err != nil {
+					return "", err
+				}
+				tag = t
+			}
+			if ef.Doc != nil {
+				doc = text(ef.Doc.Pos(), ef.Doc.End()) + "\n"
+			}
+			if ef.Tag != nil {
+				merged, err := mergeTag(ef.Tag.Value, tag)
+				if err != nil {
+					return "", err
+				}
+				tag = merged
+			}
+			if ef.Comment != nil {
+				comment = text(ef.Comment.Pos(), ef.Comment.End())
+			}
+		}
+		line := doc + name + " " + typ.String()
+		if tag != "" {
+			line += " " + tag
+		}
+		if comment != "" {
+			line += " " + comment
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, preserved...)
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// mergeTag replaces the migu tag of the existing tag with that of the generated tag, and adds the other tags of the generated tag that do not exist.
+// Both tags are quoted by backquotes.
+func mergeTag(existingTag, generatedTag string) (string, error) {
+	existing, err := parseTagPairs(existingTag)
+	if err != nil {
+		return "", err
+	}
+	generated, err := parseTagPairs(generatedTag)
+	if err != nil {
+		return "", err
+	}
+	values := map[string]string{}
+	for _, pair := range generated {
+		values[pair[0]] = pair[1]
+	}
+	seen := map[string]struct{}{}
+	var tags []string
+	for _, pair := range existing {
+		seen[pair[0]] = struct{}{}
+		value := pair[1]
+		if v, ok := values[pair[0]]; ok && pair[0] == "migu" {
+			value = v
+		}
+		tags = append(tags, pair[0]+":"+value)
+	}
+	for _, pair := range generated {
+		if _, ok := seen[pair[0]]; !ok {
+			tags = append(tags, pair[0]+":"+pair[1])
+		}
+	}
+	return "`" + strings.Join(tags, " ") + "`", nil
+}
+
+// addColumnOption adds the column option to the migu tag of the tag that is quoted by backquotes.
+func addColumnOption(tag, column string) (string, error) {
+	if tag == "" {
+		tag = "``"
+	}
+	pairs, err := parseTagPairs(tag)
+	if err != nil {
+		return "", err
+	}
+	opt := tagColumn + ":" + column
+	tags := make([]string, 0, len(pairs)+1)
+	found := false
+	for _, pair := range pairs {
+		if pair[0] == "migu" {
+			value, err := strconv.Unquote(pair[1])
+			if err != nil {
+				return "", err
+			}
+			if value != "" {
+				opt += "," + value
+			}
+			pair[1] = strconv.Quote(opt)
+			found = true
+		}
+		tags = append(tags, pair[0]+":"+pair[1])
+	}
+	if !found {
+		tags = append([]string{"migu:" + strconv.Quote(opt)}, tags...)
+	}
+	return "`" + strings.Join(tags, " ") + "`", nil
+}
+
+// parseTagPairs parses the struct tag that is quoted by backquotes into the pairs of the key and the quoted value.
+func parseTagPairs(tag string) ([][2]string, error) {
+	tag, err := strconv.Unquote(tag)
+	if err != nil {
+		return nil, err
+	}
+	var pairs [][2]string
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		if tag == "" {
+			break
+		}
+		i := strings.Index(tag, ":\"")
+		if i <= 0 {
+			return nil, fmt.Errorf("migu: invalid struct tag: %s", tag)
+		}
+		key := tag[:i]
+		rest := tag[i+1:]
+		j := 1
+		for ; j < len(rest) && rest[j] != '"'; j++ {
+			if rest[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(rest) {
+			return nil, fmt.Errorf("migu: invalid struct tag: %s", tag)
+		}
+		pairs = append(pairs, [2]string{key, rest[:j+1]})
+		tag = rest[j+1:]
+	}
+	return pairs, nil
+}
+
+// addImports adds the import declarations of pkgs that are not imported by src.
+func addImports(src []byte, pkgs []string) ([]byte, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	imported := map[string]struct{}{}
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		imported[path] = struct{}{}
+	}
+	var decls []string
+	for _, pkg := range pkgs {
+		if _, ok := imported[pkg]; ok {
+			continue
+		}
+		imported[pkg] = struct{}{}
+		decls = append(decls, fmt.Sprintf("import %q\n", pkg))
+	}
+	if len(decls) == 0 {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	f, err = parser.ParseFile(fset, "", src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, err
+	}
+	i := fset.Position(f.Name.End()).Offset
+	var buf bytes.Buffer
+	buf.Write(src[:i])
+	buf.WriteString("\n\n")
+	buf.WriteString(strings.Join(decls, ""))
+	buf.Write(src[i:])
+	return buf.Bytes(), nil
+}
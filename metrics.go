@@ -0,0 +1,15 @@
+package migu
+
+import "time"
+
+// Metrics is the interface to instrument the migrations, such as by Prometheus.
+// The methods are called synchronously, so they should return quickly.
+type Metrics interface {
+	// ObserveStatement is called after each statement is executed by Sync.
+	// err is the error of the statement, or nil if the statement succeeded.
+	ObserveStatement(op Operation, duration time.Duration, err error)
+
+	// ObserveDrift is called after CheckDrift and each check of Watch compare the schemas.
+	// drifted reports whether the database schema has drifted from Go's structs.
+	ObserveDrift(drifted bool)
+}
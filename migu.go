@@ -1,19 +1,23 @@
 package migu
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/naoina/go-stringutil"
 	"github.com/naoina/migu/dialect"
@@ -32,166 +36,1060 @@ const (
 // If src != nil, Sync parses the source from src and filename is not used.
 // The type of the argument for the src parameter must be string, []byte, or
 // io.Reader. If src == nil, Sync parses the file specified by filename.
+// If src == nil and filename is StdinFilename ("-"), Sync parses the source
+// from the standard input, so that Go's structs can be piped in.
 //
-// All query for synchronization will be performed within the transaction if
-// storage engine supports the transaction. (e.g. MySQL's MyISAM engine does
-// NOT support the transaction)
-func Sync(d dialect.Dialect, filename string, src interface{}) error {
-	sqls, err := Diff(d, filename, src)
+// Each statement for synchronization is performed within its own transaction,
+// because most DDL statements cause an implicit commit. Use WithRetry to retry
+// the statements that fail with lock wait timeouts or deadlocks.
+// If the dialect implements dialect.SessionPinner, the whole synchronization
+// is performed on a single database session unless the database cannot provide it.
+//
+// If WithHistory option is given, Sync records each applied statement into
+// the history table and skips the statements of the same plan that have already been applied.
+//
+// The rows of the slice literal of the struct that is annotated by "//+migu"
+// are upserted as the seed data after the schema is synchronized.
+// The rows are not compared with the existing rows, so the upserts are executed
+// on every synchronization even if the rows are unchanged.
+// Use WithSeedPrune to delete the rows that are removed from the seed data.
+//
+// If WithProgressStore option is given, Sync resumes the interrupted
+// synchronization that is recorded in the store instead of re-planning.
+//
+// If a statement fails, Sync returns an *ExecError that holds the failed
+// statement, the statements that have been applied, and the error from the
+// database driver.
+func Sync(d dialect.Dialect, filename string, src interface{}, opts ...Option) error {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return err
+	}
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return err
+	}
+	seeds, err := loadSeeds(filename, src)
+	if err != nil {
+		return err
+	}
+	_, err = syncStructASTMap(d, structASTMap, seeds, newOption(opts...))
+	return err
+}
+
+// SyncReport is like Sync, but also returns the report of the synchronization.
+// If a statement fails, SyncReport returns the report of the statements until the failure with the error.
+func SyncReport(d dialect.Dialect, filename string, src interface{}, opts ...Option) (*Report, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := loadSeeds(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return syncStructASTMap(d, structASTMap, seeds, newOption(opts...))
+}
+
+// SyncFS is like Sync, but reads Go's structs from the files in fsys that match any of patterns.
+// The syntax of patterns is the same as in fs.Glob. If no patterns are given, SyncFS reads all "*.go" files in the root of fsys.
+func SyncFS(d dialect.Dialect, fsys fs.FS, patterns []string, opts ...Option) error {
+	structASTMap, err := loadStructASTMapFS(fsys, patterns)
 	if err != nil {
 		return err
 	}
-	tx, err := d.Begin()
-	if err != nil {
+	seeds, err := loadSeedsFS(fsys, patterns)
+	if err != nil {
+		return err
+	}
+	_, err = syncStructASTMap(d, structASTMap, seeds, newOption(opts...))
+	return err
+}
+
+// syncStructASTMap synchronizes the database with structASTMap on a single database session if the dialect supports it.
+func syncStructASTMap(d dialect.Dialect, structASTMap map[string]*structAST, seeds []*seed, o *option) (report *Report, err error) {
+	ctx, end := o.startSpan(o.context(), "migu.Sync")
+	defer func() {
+		end(err)
+		if o.notifier != nil {
+			o.notifier(report, err)
+		}
+	}()
+	d, release, err := pinSession(ctx, d, o)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := release(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	if o.progress != nil {
+		progress, err := o.progress.LoadProgress()
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			structMap, err := makeTableMap(d, structASTMap, o)
+			if err != nil {
+				return nil, err
+			}
+			if fingerprint := newSchema(structMap).Fingerprint(); progress.Fingerprint != fingerprint {
+				return nil, fmt.Errorf("migu: the interrupted synchronization was planned from the different Go's structs; clear the progress to synchronize again")
+			}
+			o.log(LogLevelInfo, "resuming the interrupted synchronization", Attribute{Key: AttributeOperations, Value: strconv.Itoa(len(progress.Operations) - progress.Completed)})
+			return applyOperations(ctx, d, progress.Operations, progress.Completed, progress.Fingerprint, o)
+		}
+	}
+	plan, err := planStructASTMap(ctx, d, structASTMap, seeds, o)
+	if err != nil {
+		return nil, err
+	}
+	return applyOperations(ctx, d, plan.Operations, 0, plan.Fingerprint, o)
+}
+
+// pinSession returns the dialect that executes all statements on a single connection if d implements dialect.SessionPinner.
+// Otherwise, it returns d as it is. If the database of d cannot provide a single connection such as a wrapper of *sql.DB,
+// d is also returned as it is with a warning. The release function must be called after use.
+func pinSession(ctx context.Context, d dialect.Dialect, o *option) (dialect.Dialect, func() error, error) {
+	noop := func() error { return nil }
+	p, ok := d.(dialect.SessionPinner)
+	if !ok {
+		return d, noop, nil
+	}
+	pinned, release, err := p.Pin(ctx)
+	if errors.Is(err, dialect.ErrNoSingleConn) {
+		o.log(LogLevelWarn, "the statements are not pinned to a single connection", Attribute{Key: AttributeError, Value: err.Error()})
+		return d, noop, nil
+	}
+	return pinned, release, err
+}
+
+// applyOperations executes ops from the index of completed.
+// fingerprint is the fingerprint of the schema that ops was planned from, which is saved with the progress.
+func applyOperations(ctx context.Context, d dialect.Dialect, ops []Operation, completed int, fingerprint string, o *option) (*Report, error) {
+	var (
+		history dialect.HistoryStore
+		audit   dialect.AuditStore
+		applied map[string]struct{}
+		err     error
+	)
+	if o.historyTable != "" {
+		h, ok := d.(dialect.HistoryStore)
+		if !ok {
+			return nil, fmt.Errorf("migu: the dialect does not support the history table")
+		}
+		if err := h.EnsureHistoryTable(o.historyTable); err != nil {
+			return nil, err
+		}
+		if applied, err = h.AppliedChecksums(o.historyTable); err != nil {
+			return nil, err
+		}
+		history = h
+	}
+	if o.auditTable != "" {
+		a, ok := d.(dialect.AuditStore)
+		if !ok {
+			return nil, fmt.Errorf("migu: the dialect does not support the audit table")
+		}
+		if err := a.EnsureAuditTable(o.auditTable); err != nil {
+			return nil, err
+		}
+		audit = a
+	}
+	report := &Report{}
+	plan := planChecksum(ops)
+	skipped := map[int]SkipReason{}
+	for i := completed; i < len(ops); i++ {
+		if o.confirm != nil && ops[i].IsDestructive() && !o.confirm(ops[i]) {
+			skipped[i] = SkipDeclined
+		} else if _, ok := applied[historyChecksum(plan, i)]; ok {
+			skipped[i] = SkipApplied
+		}
+	}
+	if err := validateNarrowing(d, ops, completed, skipped); err != nil {
+		return report, err
+	}
+	if o.shadow {
+		if err := validateOnShadow(d, ops, completed, skipped); err != nil {
+			return report, err
+		}
+	}
+	size := multiStatementSize(d, o)
+	for i := completed; i < len(ops); {
+		if o.progress != nil {
+			if err := o.progress.SaveProgress(&Progress{
+				Operations:  ops,
+				Completed:   i,
+				Fingerprint: fingerprint,
+			}); err != nil {
+				return report, err
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if reason, ok := skipped[i]; ok {
+			o.log(LogLevelInfo, "skipped the statement", append(operationAttributes(ops[i]), Attribute{Key: AttributeReason, Value: reason.String()})...)
+			report.Skipped = append(report.Skipped, &SkippedStatement{
+				Operation: ops[i],
+				Reason:    reason,
+			})
+			i++
+			continue
+		}
+		n := 1
+		for ; n < size && i+n < len(ops); n++ {
+			if _, ok := skipped[i+n]; ok {
+				break
+			}
+		}
+		batch := ops[i : i+n]
+		start := time.Now()
+		execCtx, end := o.startExecSpan(ctx, batch)
+		var (
+			rowsAffected int64
+			executed     int
+			err          error
+		)
+		// The history of the single statement is recorded in the same transaction if the dialect allows it.
+		var recordInTx func(tx dialect.Transactioner) error
+		if h, ok := history.(dialect.HistorySQLer); ok && n == 1 {
+			recordInTx = func(tx dialect.Transactioner) error {
+				query, args := h.RecordHistorySQL(o.historyTable, dialect.History{
+					Checksum:  historyChecksum(plan, i),
+					SQL:       batch[0].SQL,
+					AppliedAt: start,
+					Duration:  time.Since(start),
+				})
+				return tx.Exec(query, args...)
+			}
+		}
+		if n == 1 {
+			if rowsAffected, err = execOperation(execCtx, d, batch[0], recordInTx, o); err == nil {
+				executed = 1
+			}
+		} else {
+			rowsAffected = -1
+			executed, err = execMultiStatements(d, batch)
+		}
+		end(err)
+		// The duration of the statements that are executed together is divided equally.
+		// If a statement fails, the statements after it are not executed.
+		attempted := executed
+		if err != nil {
+			attempted++
+		}
+		duration := time.Since(start) / time.Duration(attempted)
+		for j, op := range batch[:attempted] {
+			if o.schemaCache != nil {
+				o.schemaCache.Invalidate(op.Table)
+			}
+			if o.metrics != nil {
+				var e error
+				if j == executed {
+					e = err
+				}
+				o.metrics.ObserveStatement(op, duration, e)
+			}
+		}
+		for j, op := range batch[:executed] {
+			o.log(LogLevelInfo, "executed the statement", append(operationAttributes(op), Attribute{Key: AttributeDuration, Value: duration.String()})...)
+			report.Executed = append(report.Executed, &ExecutedStatement{
+				Operation:    op,
+				Duration:     duration,
+				RowsAffected: rowsAffected,
+			})
+			if history != nil && recordInTx == nil {
+				if err := history.RecordHistory(o.historyTable, dialect.History{
+					Checksum:  historyChecksum(plan, i+j),
+					SQL:       op.SQL,
+					AppliedAt: start,
+					Duration:  duration,
+				}); err != nil {
+					return report, err
+				}
+			}
+			if audit != nil {
+				if err := audit.RecordAudit(o.auditTable, dialect.Audit{
+					Kind:       op.Kind.String(),
+					Table:      op.Table,
+					Column:     op.Column,
+					SQL:        op.SQL,
+					AppliedAt:  start,
+					Duration:   duration,
+					User:       o.auditMetadata.User,
+					Host:       o.auditMetadata.Host,
+					AppVersion: o.auditMetadata.AppVersion,
+				}); err != nil {
+					return report, err
+				}
+			}
+		}
+		if err != nil {
+			failed := batch[executed]
+			o.log(LogLevelError, "failed to execute the statement", append(operationAttributes(failed), Attribute{Key: AttributeError, Value: err.Error()})...)
+			// The statements before the failed one have been applied, so resuming must start from the failed one.
+			if o.progress != nil && executed > 0 {
+				if err := o.progress.SaveProgress(&Progress{
+					Operations:  ops,
+					Completed:   i + executed,
+					Fingerprint: fingerprint,
+				}); err != nil {
+					return report, err
+				}
+			}
+			return report, &ExecError{
+				Operation: failed,
+				Index:     i + executed,
+				Applied:   report.operations(),
+				Err:       err,
+			}
+		}
+		i += n
+	}
+	if o.progress != nil {
+		if err := o.progress.ClearProgress(); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// validateOnShadow executes the operations that are not skipped on the shadow database.
+func validateOnShadow(d dialect.Dialect, ops []Operation, completed int, skipped map[int]SkipReason) error {
+	sh, ok := d.(dialect.Shadower)
+	if !ok {
+		return fmt.Errorf("migu: the dialect does not support the shadow database")
+	}
+	var (
+		sqls    []string
+		indexes []int
+	)
+	for i := completed; i < len(ops); i++ {
+		if _, ok := skipped[i]; !ok {
+			sqls = append(sqls, ops[i].SQL)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(sqls) == 0 {
+		return nil
+	}
+	failed, err := sh.ExecShadow(sqls)
+	if err == nil {
+		return nil
+	}
+	if failed < 0 {
+		return err
+	}
+	i := indexes[failed]
+	return fmt.Errorf("migu: validation on the shadow database failed: %w", &ExecError{
+		Operation: ops[i],
+		Index:     i,
+		Err:       err,
+	})
+}
+
+// multiStatementSize returns the maximum number of the statements that are executed in a single call.
+// It returns 1 unless WithMultiStatements option is given and the connection of d permits the multiple statements.
+func multiStatementSize(d dialect.Dialect, o *option) int {
+	if o.multiStatements <= 1 {
+		return 1
+	}
+	m, ok := d.(dialect.MultiStatementExecer)
+	if !ok || !m.SupportsMultiStatements() {
+		return 1
+	}
+	return o.multiStatements
+}
+
+// execMultiStatements executes the SQLs of ops in a single call.
+func execMultiStatements(d dialect.Dialect, ops []Operation) (int, error) {
+	sqls := make([]string, len(ops))
+	for i, op := range ops {
+		sqls[i] = op.SQL
+	}
+	return d.(dialect.MultiStatementExecer).ExecMultiStatements(sqls)
+}
+
+// execOperation executes op in its own transaction.
+// Most DDL statements cause an implicit commit, so the operations cannot be executed in a single transaction anyway.
+// If after is not nil, it is called in the transaction after op is executed successfully.
+// If the error is retryable on d, execOperation retries it up to the times that is set by WithRetry.
+// It returns the number of rows affected by op, or -1 if it is unknown.
+func execOperation(ctx context.Context, d dialect.Dialect, op Operation, after func(tx dialect.Transactioner) error, o *option) (int64, error) {
+	backoff := o.retryBackoff
+	for retry := 0; ; retry++ {
+		rowsAffected, err := execInTransaction(d, op.SQL, after)
+		if err == nil {
+			return rowsAffected, nil
+		}
+		r, ok := d.(dialect.Retryable)
+		if !ok || !r.IsRetryable(err) || retry >= o.maxRetries {
+			return 0, err
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func execInTransaction(d dialect.Dialect, sql string, after func(tx dialect.Transactioner) error) (int64, error) {
+	tx, err := d.Begin()
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected := int64(-1)
+	if e, ok := tx.(dialect.RowsAffectedExecer); ok {
+		rowsAffected, err = e.ExecRowsAffected(sql)
+	} else {
+		err = tx.Exec(sql)
+	}
+	if err == nil && after != nil {
+		err = after(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return rowsAffected, tx.Commit()
+}
+
+// Diff returns SQLs for schema synchronous between database and Go's struct.
+func Diff(d dialect.Dialect, filename string, src interface{}, opts ...Option) ([]string, error) {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return diffStructASTMap(d, structASTMap, newOption(opts...))
+}
+
+// DiffFS is like Diff, but reads Go's structs from the files in fsys that match any of patterns.
+// The syntax of patterns is the same as in fs.Glob. If no patterns are given, DiffFS reads all "*.go" files in the root of fsys.
+// It is useful for diffing the schema definitions that are embedded by go:embed directive.
+func DiffFS(d dialect.Dialect, fsys fs.FS, patterns []string, opts ...Option) ([]string, error) {
+	structASTMap, err := loadStructASTMapFS(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	return diffStructASTMap(d, structASTMap, newOption(opts...))
+}
+
+// DiffFiles returns SQLs for schema synchronous from the old Go's structs to the new Go's structs without a database connection.
+// The old and new structs are read in the same way as Diff reads filename and src.
+// d is used only for generating SQLs, so a dialect that has no database connection (e.g. dialect.NewMySQL(nil)) can be used.
+// Only one of the old and new structs can be read from the standard input.
+func DiffFiles(d dialect.Dialect, oldFilename string, oldSrc interface{}, newFilename string, newSrc interface{}, opts ...Option) ([]string, error) {
+	if oldSrc == nil && newSrc == nil && oldFilename == StdinFilename && newFilename == StdinFilename {
+		return nil, fmt.Errorf("migu: both the old and new structs cannot be read from the standard input")
+	}
+	oldStructASTMap, err := loadStructASTMap(oldFilename, oldSrc)
+	if err != nil {
+		return nil, err
+	}
+	newStructASTMap, err := loadStructASTMap(newFilename, newSrc)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	oldMap, err := makeTableMap(d, oldStructASTMap, o)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := makeTableMap(d, newStructASTMap, o)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkAmbiguities(newMap); err != nil {
+		return nil, err
+	}
+	if err := o.validateIdentifiers(d, newMap); err != nil {
+		return nil, err
+	}
+	ops, err := diffTables(d, oldMap, newMap, o)
+	if err != nil {
+		return nil, err
+	}
+	return o.operationSQLs(ops), nil
+}
+
+// DiffOperations is like Diff, but returns the operations that hold the SQLs with the details.
+func DiffOperations(d dialect.Dialect, filename string, src interface{}, opts ...Option) ([]Operation, error) {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return diffOperations(d, structASTMap, newOption(opts...))
+}
+
+func diffStructASTMap(d dialect.Dialect, structASTMap map[string]*structAST, opt *option) ([]string, error) {
+	ops, err := diffOperations(d, structASTMap, opt)
+	if err != nil {
+		return nil, err
+	}
+	return opt.operationSQLs(ops), nil
+}
+
+// diffOperations returns the operations to migrate the database to structASTMap in "migu.Diff" span.
+func diffOperations(d dialect.Dialect, structASTMap map[string]*structAST, opt *option) (ops []Operation, err error) {
+	ctx, end := opt.startSpan(opt.context(), "migu.Diff")
+	defer func() {
+		end(err)
+	}()
+	structMap, err := makeTableMap(d, structASTMap, opt)
+	if err != nil {
+		return nil, err
+	}
+	return diff(ctx, d, structMap, opt)
+}
+
+func diff(ctx context.Context, d dialect.Dialect, structMap map[string]*table, opt *option) ([]Operation, error) {
+	if err := opt.checkAmbiguities(structMap); err != nil {
+		return nil, err
+	}
+	if err := opt.validateIdentifiers(d, structMap); err != nil {
+		return nil, err
+	}
+	_, end := opt.startSpan(ctx, "migu.Introspect")
+	oldMap, err := inspectTableMap(d, structMap, opt)
+	end(err)
+	if err != nil {
+		return nil, err
+	}
+	opt.log(LogLevelDebug, "introspected the database", Attribute{Key: AttributeTables, Value: strconv.Itoa(len(oldMap))})
+	opt.logPromotions(structMap)
+	ops, err := diffTables(d, oldMap, structMap, opt)
+	if err != nil {
+		return nil, err
+	}
+	opt.log(LogLevelDebug, "planned the operations", Attribute{Key: AttributeOperations, Value: strconv.Itoa(len(ops))})
+	return ops, nil
+}
+
+// logPromotions logs the columns whose VARCHAR type was promoted to the TEXT type in LogLevelWarn.
+func (o *option) logPromotions(structMap map[string]*table) {
+	names := make([]string, 0, len(structMap))
+	for name := range structMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, f := range structMap[name].Fields {
+			if f.PromotedFrom != "" {
+				o.log(LogLevelWarn, fmt.Sprintf("column `%s' has %s type that exceeds the limit, so it is created as %s type", f.Column, f.PromotedFrom, f.Type), Attribute{Key: AttributeTable, Value: name})
+			}
+		}
+	}
+}
+
+// inspectTableMap returns the tables on the database that are compared with structMap.
+// Only the tables of structMap are retrieved unless WithDropUnknownTables option is given.
+// The history table and the archived tables are excluded.
+func inspectTableMap(d dialect.Dialect, structMap map[string]*table, opt *option) (map[string]*table, error) {
+	var names []string
+	if !opt.dropUnknownTables {
+		for name := range structMap {
+			names = append(names, name)
+		}
+	}
+	var (
+		tableMap map[string][]dialect.ColumnSchema
+		err      error
+	)
+	if opt.schemaCache != nil {
+		tableMap, err = opt.schemaCache.tableMap(d, names...)
+	} else {
+		tableMap, err = getTableMap(d, names...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for name := range tableMap {
+		if isBookkeepingTable(name, opt) {
+			delete(tableMap, name)
+		}
+	}
+	if l, ok := d.(dialect.ViewLister); ok && opt.dropUnknownTables {
+		views, err := l.Views()
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range views {
+			if _, ok := structMap[name]; !ok {
+				delete(tableMap, name)
+			}
+		}
+	}
+	if unsupported := opt.filterUnsupportedTypes(d, tableMap); len(unsupported) > 0 {
+		removeUnsupportedColumns(structMap, tableMap, unsupported)
+	}
+	m, err := makeTableMapFromColumnSchemas(d, tableMap, opt)
+	if err != nil {
+		return nil, err
+	}
+	if err := inspectTableOptions(d, m, structMap); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// inspectTableOptions sets the options of the tables of tableMap if d is dialect.TableOptioner.
+// The options are retrieved only if any struct of structMap specifies them.
+func inspectTableOptions(d dialect.Dialect, tableMap, structMap map[string]*table) error {
+	o, ok := d.(dialect.TableOptioner)
+	if !ok {
+		return nil
+	}
+	var names []string
+	for name, tbl := range structMap {
+		if _, exists := tableMap[name]; exists && !tbl.Options.IsZero() {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	options, err := o.TableOptions(names...)
+	if err != nil {
+		return err
+	}
+	for name, opts := range options {
+		if tbl := tableMap[name]; tbl != nil {
+			tbl.Options = opts
+		}
+	}
+	return nil
+}
+
+// diffTables returns the operations to migrate the tables from oldMap to newMap.
+// It returns *SafetyError if the operations violate the safety policy of opt.
+func diffTables(d dialect.Dialect, oldMap, newMap map[string]*table, opt *option) ([]Operation, error) {
+	opt.archivedAt = time.Now()
+	oldMap, err := opt.filterTables(oldMap)
+	if err != nil {
+		return nil, err
+	}
+	if newMap, err = opt.filterTables(newMap); err != nil {
+		return nil, err
+	}
+	if opt.caseInsensitiveNames {
+		oldMap = foldNameCase(oldMap, newMap)
+	}
+	names := make([]string, 0, len(newMap))
+	for name := range newMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = sortTablesByDependency(names, newMap)
+	results := make([][]Operation, len(names))
+	parallelDo(len(names), opt.concurrency(), func(i int) error {
+		name := names[i]
+		results[i] = diffTable(d, name, oldMap[name], newMap[name], opt)
+		return nil
+	})
+	var migrations []Operation
+	for _, ops := range results {
+		migrations = append(migrations, ops...)
+	}
+	var dropNames []string
+	for name := range oldMap {
+		if _, ok := newMap[name]; !ok {
+			dropNames = append(dropNames, name)
+		}
+	}
+	sort.Strings(dropNames)
+	// The tables are dropped in the reverse order of the dependency so that the referring tables are dropped first.
+	// The foreign keys of oldMap are read from the database by dialect.ForeignKeyColumnSchema.
+	dropNames = sortTablesByDependency(dropNames, oldMap)
+	for i, j := 0, len(dropNames)-1; i < j; i, j = i+1, j-1 {
+		dropNames[i], dropNames[j] = dropNames[j], dropNames[i]
+	}
+	for _, name := range dropNames {
+		if a, ok := opt.archiver(d); ok {
+			archive := archiveTableName(opt.archivedAt, name)
+			migrations = append(migrations, newOperations(OperationArchive, name, "", a.RenameTableSQL(name, archive), a.RenameTableSQL(archive, name))...)
+			continue
+		}
+		down := createTableSQL(d, name, oldMap[name])
+		indexes, _ := makeIndexes(nil, oldMap[name].Fields)
+		for _, index := range indexes {
+			down = append(down, d.CreateIndexSQL(index.ToIndex())...)
+		}
+		migrations = append(migrations, newOperations(OperationDropTable, name, "", d.DropTableSQL(dialect.Table{
+			Name: name,
+		}), down)...)
+	}
+	migrations = opt.excludeReadOnly(migrations, oldMap, newMap)
+	if err := opt.checkSafety(migrations); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func loadStructASTMap(filename string, src interface{}) (map[string]*structAST, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	filenames, err := sourceFilenames(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	structASTMap := make(map[string]*structAST)
+	for _, filename := range filenames {
+		m, err := makeStructASTMap(fset, filename, src)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range m {
+			structASTMap[k] = v
+		}
+	}
+	return structASTMap, nil
+}
+
+func loadStructASTMapFS(fsys fs.FS, patterns []string) (map[string]*structAST, error) {
+	filenames, err := globFS(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	structASTMap := make(map[string]*structAST)
+	for _, filename := range filenames {
+		src, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		m, err := makeStructASTMap(fset, filename, src)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range m {
+			structASTMap[k] = v
+		}
+	}
+	return structASTMap, nil
+}
+
+// globFS returns the names of the files in fsys that match any of patterns.
+// If no patterns are given, globFS returns all "*.go" files in the root of fsys.
+func globFS(fsys fs.FS, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"*.go"}
+	}
+	var filenames []string
+	seen := map[string]struct{}{}
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matches {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			filenames = append(filenames, name)
+		}
+	}
+	return filenames, nil
+}
+
+// makeTableMap returns the tables that are made from structASTMap, keyed by the table name.
+// The tables are made concurrently because a large package has thousands of fields.
+func makeTableMap(d dialect.Dialect, structASTMap map[string]*structAST, opt *option) (map[string]*table, error) {
+	names := make([]string, 0, len(structASTMap))
+	for name := range structASTMap {
+		names = append(names, name)
+	}
+	tables := make([]*table, len(names))
+	if err := parallelDo(len(names), opt.concurrency(), func(i int) (err error) {
+		tables[i], err = makeTableFromStructAST(d, names[i], structASTMap[names[i]])
 		return err
+	}); err != nil {
+		return nil, err
 	}
-	for _, sql := range sqls {
-		if err := tx.Exec(sql); err != nil {
-			tx.Rollback()
-			return err
+	structMap := make(map[string]*table, len(names))
+	for i, name := range names {
+		if tables[i] != nil {
+			structMap[name] = tables[i]
 		}
 	}
-	return tx.Commit()
+	if err := addHistoryTables(d, structMap); err != nil {
+		return nil, err
+	}
+	return structMap, nil
 }
 
-// Diff returns SQLs for schema synchronous between database and Go's struct.
-func Diff(d dialect.Dialect, filename string, src interface{}) ([]string, error) {
-	var filenames []string
-	structASTMap := make(map[string]*structAST)
-	if src == nil {
-		files, err := collectFiles(filename)
+// makeTableFromStructAST returns the table that is made from structAST.
+// It returns nil if the struct has no fields of the columns.
+func makeTableFromStructAST(d dialect.Dialect, name string, structAST *structAST) (*table, error) {
+	var tbl *table
+	for _, fld := range structAST.StructType.Fields.List {
+		typeName, err := detectTypeName(fld)
 		if err != nil {
 			return nil, err
 		}
-		filenames = files
-	} else {
-		filenames = append(filenames, filename)
-	}
-	for _, filename := range filenames {
-		m, err := makeStructASTMap(filename, src)
+		f, err := newField(d, name, typeName, fld)
 		if err != nil {
 			return nil, err
 		}
-		for k, v := range m {
-			structASTMap[k] = v
+		if f.Ignore {
+			continue
 		}
-	}
-	structMap := map[string]*table{}
-	for name, structAST := range structASTMap {
-		for _, fld := range structAST.StructType.Fields.List {
-			typeName, err := detectTypeName(fld)
-			if err != nil {
-				return nil, err
-			}
-			f, err := newField(d, name, typeName, fld)
-			if err != nil {
-				return nil, err
-			}
-			if f.Ignore {
-				continue
-			}
-			if !(ast.IsExported(f.Name) || (f.Name == "_" && f.Name != f.Column)) {
-				continue
+		f.Pos = fld.Pos()
+		if !(ast.IsExported(f.Name) || (f.Name == "_" && f.Name != f.Column)) {
+			continue
+		}
+		if tbl == nil {
+			tbl = &table{
+				Options:  structAST.Annotation.Options,
+				Option:   structAST.Annotation.Option,
+				ReadOnly: structAST.Annotation.ReadOnly,
+				History:  structAST.Annotation.History,
+				Fields:   make([]*field, 0, len(structAST.StructType.Fields.List)),
+				Fset:     structAST.Fset,
+				Pos:      structAST.StructType.Pos(),
 			}
-			if structMap[name] == nil {
-				structMap[name] = &table{
-					Option: structAST.Annotation.Option,
-				}
+		}
+		if p, ok := d.(dialect.VarcharPromoter); ok {
+			if typ, promoted := p.PromoteVarchar(f.Type, tbl.Options); promoted {
+				f.PromotedFrom, f.Type = f.Type, typ
 			}
-			structMap[name].Fields = append(structMap[name].Fields, f)
 		}
+		tbl.Fields = append(tbl.Fields, f)
 	}
-	names := make([]string, 0, len(structMap))
-	for name := range structMap {
+	return tbl, nil
+}
+
+func makeTableMapFromColumnSchemas(d dialect.Dialect, tableMap map[string][]dialect.ColumnSchema, opt *option) (map[string]*table, error) {
+	names := make([]string, 0, len(tableMap))
+	for name := range tableMap {
 		names = append(names, name)
 	}
-	tableMap, err := getTableMap(d, names...)
-	if err != nil {
+	tables := make([]*table, len(names))
+	if err := parallelDo(len(names), opt.concurrency(), func(i int) (err error) {
+		tables[i], err = makeTableFromColumnSchemas(d, names[i], tableMap[names[i]])
+		return err
+	}); err != nil {
 		return nil, err
 	}
-	sort.Strings(names)
-	var migrations []string
+	m := make(map[string]*table, len(names))
+	for i, name := range names {
+		m[name] = tables[i]
+	}
+	return m, nil
+}
+
+func makeTableFromColumnSchemas(d dialect.Dialect, name string, columns []dialect.ColumnSchema) (*table, error) {
+	tbl := &table{
+		Fields: make([]*field, 0, len(columns)),
+	}
+	reordered := reorderedIndexes(columns)
+	for _, c := range columns {
+		oldFieldAST, err := fieldAST(d, c, reordered)
+		if err != nil {
+			return nil, err
+		}
+		typeName, err := detectTypeName(oldFieldAST.Type)
+		if err != nil {
+			return nil, err
+		}
+		f, err := newField(d, name, typeName, oldFieldAST)
+		if err != nil {
+			return nil, err
+		}
+		tbl.Fields = append(tbl.Fields, f)
+	}
+	return tbl, nil
+}
+
+// diffTable returns the operations to migrate the table from oldTbl to newTbl.
+// If oldTbl is nil, the table will be created.
+func diffTable(d dialect.Dialect, name string, oldTbl, newTbl *table, opt *option) []Operation {
+	var migrations []Operation
+	var oldFields []*field
 	droppedColumn := map[string]struct{}{}
-	for _, name := range names {
-		tbl := structMap[name]
-		var oldFields []*field
-		if columns, ok := tableMap[name]; ok {
-			for _, c := range columns {
-				oldFieldAST, err := fieldAST(d, c)
-				if err != nil {
-					return nil, err
+	if oldTbl != nil {
+		oldFields = oldTbl.Fields
+		fields := makeAlterTableFields(oldFields, newTbl.Fields)
+		for _, f := range fields {
+			switch {
+			case f.IsAdded():
+				if backfill := opt.backfillSQL(d, f.new); backfill != "" {
+					migrations = append(migrations, backfillOperations(d, f.new, backfill)...)
+					continue
 				}
-				f, err := newField(d, name, fmt.Sprint(oldFieldAST.Type), oldFieldAST)
-				if err != nil {
-					return nil, err
+				migrations = append(migrations, withSafety(addColumnSafety(f.new), newOperations(OperationAddColumn, name, f.new.Column, d.AddColumnSQL(f.new.ToField()), d.DropColumnSQL(f.new.ToField())))...)
+			case f.IsDropped():
+				down := d.AddColumnSQL(f.old.ToField())
+				indexes, _ := makeIndexes(nil, []*field{f.old})
+				for _, index := range indexes {
+					down = append(down, d.CreateIndexSQL(index.ToIndex())...)
 				}
-				oldFields = append(oldFields, f)
-			}
-			fields := makeAlterTableFields(oldFields, tbl.Fields)
-			for _, f := range fields {
-				switch {
-				case f.IsAdded():
-					migrations = append(migrations, d.AddColumnSQL(f.new.ToField())...)
-				case f.IsDropped():
-					migrations = append(migrations, d.DropColumnSQL(f.old.ToField())...)
-				case f.IsModified():
-					migrations = append(migrations, d.ModifyColumnSQL(f.old.ToField(), f.new.ToField())...)
-				}
-			}
-			if d, ok := d.(dialect.PrimaryKeyModifier); ok {
-				oldPks, newPks := makePrimaryKeyColumns(oldFields, tbl.Fields)
-				if len(oldPks) > 0 || len(newPks) > 0 {
-					oldPrimaryKeyFields := make([]dialect.Field, len(oldPks))
+				if a, ok := opt.archiver(d); ok {
+					archive := archiveTableName(opt.archivedAt, name, f.old.Column)
+					oldPks, _ := makePrimaryKeyColumns(oldFields, nil)
+					pkColumns := make([]string, len(oldPks))
 					for i, pk := range oldPks {
-						oldPrimaryKeyFields[i] = pk.ToField()
+						pkColumns[i] = pk.Column
 					}
-					newPrimaryKeyFields := make([]dialect.Field, len(newPks))
-					for i, pk := range newPks {
-						newPrimaryKeyFields[i] = pk.ToField()
-					}
-					migrations = append(migrations, d.ModifyPrimaryKeySQL(oldPrimaryKeyFields, newPrimaryKeyFields)...)
+					migrations = append(migrations, newOperations(OperationArchive, name, f.old.Column, a.ArchiveColumnSQL(archive, f.old.ToField(), pkColumns), d.DropTableSQL(dialect.Table{
+						Name: archive,
+					}))...)
 				}
-			}
-			for _, f := range fields {
-				if f.IsDropped() {
-					droppedColumn[f.old.Column] = struct{}{}
+				migrations = append(migrations, newOperations(OperationDropColumn, name, f.old.Column, d.DropColumnSQL(f.old.ToField()), down)...)
+			case f.IsModified() && opt.commentMode != CommentModify && f.old.IsCommentOnlyDifferent(f.new):
+				if opt.commentMode == CommentSeparate {
+					migrations = append(migrations, newOperations(OperationModifyComment, name, f.new.Column, d.ModifyColumnSQL(f.old.ToField(), f.new.ToField()), d.ModifyColumnSQL(f.new.ToField(), f.old.ToField()))...)
 				}
+			case f.IsModified():
+				ops := withSafety(modifyColumnSafety(f.old, f.new), newOperations(OperationModifyColumn, name, f.new.Column, d.ModifyColumnSQL(f.old.ToField(), f.new.ToField()), d.ModifyColumnSQL(f.new.ToField(), f.old.ToField())))
+				if opt.narrowingValidation && len(ops) > 0 {
+					ops[0].Validation = narrowingValidation(d, name, f.old, f.new)
+				}
+				migrations = append(migrations, ops...)
 			}
-		} else {
-			fields := make([]dialect.Field, len(tbl.Fields))
-			for i, f := range tbl.Fields {
-				fields[i] = f.ToField()
+		}
+		if d, ok := d.(dialect.PrimaryKeyModifier); ok {
+			oldPks, newPks := makePrimaryKeyColumns(oldFields, newTbl.Fields)
+			if len(oldPks) > 0 || len(newPks) > 0 {
+				oldPrimaryKeyFields := make([]dialect.Field, len(oldPks))
+				for i, pk := range oldPks {
+					oldPrimaryKeyFields[i] = pk.ToField()
+				}
+				newPrimaryKeyFields := make([]dialect.Field, len(newPks))
+				for i, pk := range newPks {
+					newPrimaryKeyFields[i] = pk.ToField()
+				}
+				migrations = append(migrations, newOperations(OperationModifyPrimaryKey, name, "", d.ModifyPrimaryKeySQL(oldPrimaryKeyFields, newPrimaryKeyFields), d.ModifyPrimaryKeySQL(newPrimaryKeyFields, oldPrimaryKeyFields))...)
 			}
-			_, newPks := makePrimaryKeyColumns(oldFields, tbl.Fields)
-			pkColumns := make([]string, len(newPks))
-			for i, pk := range newPks {
-				pkColumns[i] = pk.ToField().Name
+		}
+		if o, ok := d.(dialect.TableOptioner); ok {
+			if up, down := diffTableOptions(oldTbl.Options, newTbl.Options); !up.IsZero() {
+				migrations = append(migrations, newOperations(OperationModifyTableOptions, name, "", o.AlterTableOptionsSQL(name, up), o.AlterTableOptionsSQL(name, down))...)
 			}
-			migrations = append(migrations, d.CreateTableSQL(dialect.Table{
-				Name:        name,
-				Fields:      fields,
-				PrimaryKeys: pkColumns,
-				Option:      tbl.Option,
-			})...)
 		}
-		addIndexes, dropIndexes := makeIndexes(oldFields, tbl.Fields)
-		for _, index := range dropIndexes {
-			// If the column which has the index will be deleted, Migu will not delete the index related to the column
-			// because the index will be deleted when the column which related to the index will be deleted.
-			if _, ok := droppedColumn[index.Columns[0]]; !ok {
-				migrations = append(migrations, d.DropIndexSQL(index.ToIndex())...)
+		for _, f := range fields {
+			if f.IsDropped() {
+				droppedColumn[f.old.Column] = struct{}{}
 			}
 		}
-		for _, index := range addIndexes {
-			migrations = append(migrations, d.CreateIndexSQL(index.ToIndex())...)
+	} else {
+		migrations = append(migrations, newOperations(OperationCreateTable, name, "", createTableSQL(d, name, newTbl), d.DropTableSQL(dialect.Table{
+			Name: name,
+		}))...)
+	}
+	addIndexes, dropIndexes := makeIndexes(oldFields, newTbl.Fields)
+	for _, index := range dropIndexes {
+		// If the column which has the index will be deleted, Migu will not delete the index related to the column
+		// because the index will be deleted when the column which related to the index will be deleted.
+		if _, ok := droppedColumn[index.Columns[0]]; !ok {
+			migrations = append(migrations, newOperations(OperationDropIndex, name, "", d.DropIndexSQL(index.ToIndex()), d.CreateIndexSQL(index.ToIndex()))...)
 		}
-		delete(structMap, name)
-		delete(tableMap, name)
 	}
-	for name := range tableMap {
-		migrations = append(migrations, fmt.Sprintf(`DROP TABLE %s`, d.Quote(name)))
+	for _, index := range addIndexes {
+		migrations = append(migrations, newOperations(OperationCreateIndex, name, "", d.CreateIndexSQL(index.ToIndex()), d.DropIndexSQL(index.ToIndex()))...)
 	}
-	return migrations, nil
+	return migrations
+}
+
+// diffTableOptions returns the options to change from oldOptions to newOptions, and the options to revert them.
+// The options that are not specified by newOptions are not changed. The names are compared case-insensitively,
+// and "DEFAULT" of the row format is not compared because the database reports the actual row format.
+func diffTableOptions(oldOptions, newOptions dialect.TableOptions) (up, down dialect.TableOptions) {
+	diff := func(oldValue, newValue string, up, down *string) {
+		if newValue != "" && !strings.EqualFold(oldValue, newValue) {
+			*up, *down = newValue, oldValue
+		}
+	}
+	diff(oldOptions.Engine, newOptions.Engine, &up.Engine, &down.Engine)
+	diff(oldOptions.Charset, newOptions.Charset, &up.Charset, &down.Charset)
+	diff(oldOptions.Collate, newOptions.Collate, &up.Collate, &down.Collate)
+	if !strings.EqualFold(newOptions.RowFormat, "DEFAULT") {
+		diff(oldOptions.RowFormat, newOptions.RowFormat, &up.RowFormat, &down.RowFormat)
+	}
+	return up, down
+}
+
+// backfillOperations returns the operations to add the NOT NULL column f by three steps:
+// add f as a nullable column, fill the column by backfill statement, and then modify the column to NOT NULL.
+func backfillOperations(d dialect.Dialect, f *field, backfill string) []Operation {
+	nullable := f.ToField()
+	nullable.Nullable = true
+	ops := newOperations(OperationAddColumn, f.Table, f.Column, d.AddColumnSQL(nullable), d.DropColumnSQL(nullable))
+	ops = append(ops, Operation{
+		Kind:   OperationBackfill,
+		Table:  f.Table,
+		Column: f.Column,
+		SQL:    backfill,
+		Safety: SafetyLocking,
+	})
+	return append(ops, withSafety(SafetyLocking, newOperations(OperationModifyColumn, f.Table, f.Column, d.ModifyColumnSQL(nullable, f.ToField()), nil))...)
+}
+
+func withSafety(safety Safety, ops []Operation) []Operation {
+	for i := range ops {
+		ops[i].Safety = safety
+	}
+	return ops
+}
+
+func createTableSQL(d dialect.Dialect, name string, tbl *table) []string {
+	fields := make([]dialect.Field, len(tbl.Fields))
+	for i, f := range tbl.Fields {
+		fields[i] = f.ToField()
+	}
+	_, pks := makePrimaryKeyColumns(nil, tbl.Fields)
+	pkColumns := make([]string, len(pks))
+	for i, pk := range pks {
+		pkColumns[i] = pk.ToField().Name
+	}
+	return d.CreateTableSQL(dialect.Table{
+		Name:        name,
+		Fields:      fields,
+		PrimaryKeys: pkColumns,
+		Options:     tbl.Options,
+		Option:      tbl.Option,
+	})
+}
+
+// StdinFilename is the filename to read Go's structs from the standard input if src is nil.
+const StdinFilename = "-"
+
+// stdinName is the filename of the source that is read from the standard input in the positions of the errors.
+const stdinName = "<standard input>"
+
+// readSource reads src if it is io.Reader so that src can be parsed more than once.
+// If src is nil and filename is StdinFilename, it reads the standard input instead.
+// Otherwise, it returns filename and src as they are.
+func readSource(filename string, src interface{}) (string, interface{}, error) {
+	if src == nil && filename == StdinFilename {
+		filename, src = stdinName, os.Stdin
+	}
+	if r, ok := src.(io.Reader); ok {
+		b, err := io.ReadAll(r)
+		return filename, b, err
+	}
+	return filename, src, nil
+}
+
+// sourceFilenames returns the filenames to read Go's structs from.
+// If src != nil, it returns filename only.
+func sourceFilenames(filename string, src interface{}) ([]string, error) {
+	if src != nil {
+		return []string{filename}, nil
+	}
+	return collectFiles(filename)
 }
 
 func collectFiles(path string) ([]string, error) {
@@ -227,7 +1125,23 @@ func collectFiles(path string) ([]string, error) {
 
 type table struct {
 	Fields []*field
+
+	// Options is the options of the table that are compared by Diff.
+	// Only the options that are specified by the struct are compared.
+	Options dialect.TableOptions
+
+	// Option is the raw table option that is appended to CREATE TABLE.
 	Option string
+
+	// ReadOnly is true if the table is never migrated by the "readonly" annotation.
+	ReadOnly bool
+
+	// History is true if the table has the history table by the "history" annotation.
+	History bool
+
+	// Fset and Pos are the position of the struct. They are not set for the tables on the database.
+	Fset *token.FileSet
+	Pos  token.Pos
 }
 
 type index struct {
@@ -261,6 +1175,23 @@ type field struct {
 	Default       string
 	Extra         string
 	Nullable      bool
+	Backfill      string
+
+	// Pos is the position of the struct field. It is not set for the columns on the database.
+	Pos token.Pos
+
+	// HasType reports whether the column type is given by `type` tag.
+	HasType bool
+
+	// ForeignKey is the column of the other table that the column refers to. (e.g. "user.id")
+	ForeignKey string
+
+	// PromotedFrom is the VARCHAR type that was promoted to Type because it exceeds the limit of the database.
+	PromotedFrom string
+
+	// IndexPositions is the positions of the column in the indexes that are given by `index:<name>:<position>` tag.
+	// The keys are the index names in RawIndexes and RawUniques.
+	IndexPositions map[string]int
 }
 
 func newField(d dialect.Dialect, tableName string, typeName string, f *ast.Field) (*field, error) {
@@ -301,6 +1232,7 @@ func newField(d dialect.Dialect, tableName string, typeName string, f *ast.Field
 		colType = strings.TrimLeft(ret.GoType, "*")
 	} else {
 		colType = ret.Type
+		ret.HasType = true
 	}
 	ret.Type = d.ColumnType(colType)
 	return ret, nil
@@ -309,10 +1241,7 @@ func newField(d dialect.Dialect, tableName string, typeName string, f *ast.Field
 func (f *field) Indexes() []string {
 	indexes := make([]string, 0, len(f.RawIndexes))
 	for _, index := range f.RawIndexes {
-		if index == "" {
-			index = stringutil.ToSnakeCase(f.Table) + "_" + f.Column
-		}
-		indexes = append(indexes, index)
+		indexes = append(indexes, f.indexName(index))
 	}
 	return indexes
 }
@@ -320,22 +1249,71 @@ func (f *field) Indexes() []string {
 func (f *field) UniqueIndexes() []string {
 	uniques := make([]string, 0, len(f.RawUniques))
 	for _, u := range f.RawUniques {
-		if u == "" {
-			u = stringutil.ToSnakeCase(f.Table) + "_" + f.Column
-		}
-		uniques = append(uniques, u)
+		uniques = append(uniques, f.indexName(u))
 	}
 	return uniques
 }
 
+// indexName returns the name of the index of the raw name in RawIndexes or RawUniques.
+func (f *field) indexName(raw string) string {
+	if raw == "" {
+		return stringutil.ToSnakeCase(f.Table) + "_" + f.Column
+	}
+	return raw
+}
+
+// IndexPosition returns the position of the column in the index named name.
+// It returns 1 if the position is not given, so that the columns are in the order of the fields.
+func (f *field) IndexPosition(name string) int {
+	for raw, pos := range f.IndexPositions {
+		if f.indexName(raw) == name {
+			return pos
+		}
+	}
+	return 1
+}
+
+// setIndexPosition sets the position of the column in the index of the raw name.
+func (f *field) setIndexPosition(raw string, pos int) {
+	if pos < 1 {
+		return
+	}
+	if f.IndexPositions == nil {
+		f.IndexPositions = map[string]int{}
+	}
+	f.IndexPositions[raw] = pos
+}
+
+// indexOption returns the value of `index` and `unique` tags for the raw name of the index.
+func (f *field) indexOption(raw string) string {
+	if pos, ok := f.IndexPositions[raw]; ok {
+		return raw + ":" + strconv.Itoa(pos)
+	}
+	return raw
+}
+
+// splitIndexPosition splits the value of `index` and `unique` tags into the index name and the position of the column.
+// The position is 0 if it is not given.
+func splitIndexPosition(value string) (name string, pos int) {
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return value, 0
+	}
+	pos, err := strconv.Atoi(value[i+1:])
+	if err != nil || pos < 1 {
+		return value, 0
+	}
+	return value[:i], pos
+}
+
 func (f *field) IsDifferent(another *field) bool {
 	if f == nil && another == nil {
 		return false
 	}
 	return ((f == nil && another != nil) || (f != nil && another == nil)) ||
-		f.Type != another.Type ||
+		normalizeColumnType(f.Type) != normalizeColumnType(another.Type) ||
 		f.Nullable != another.Nullable ||
-		f.Default != another.Default ||
+		normalizeDefault(f.Type, f.Default) != normalizeDefault(another.Type, another.Default) ||
 		f.Column != another.Column ||
 		f.Extra != another.Extra ||
 		f.Comment != another.Comment ||
@@ -460,9 +1438,26 @@ func makeIndexes(oldFields, newFields []*field) (addIndexes, dropIndexes []*inde
 	for _, name := range dropIndexNames {
 		dropIndexes = append(dropIndexes, dropIndexMap[name])
 	}
+	sortIndexColumns(addIndexes, newFields)
+	sortIndexColumns(dropIndexes, oldFields)
 	return addIndexes, dropIndexes
 }
 
+// sortIndexColumns sorts the columns of the indexes by the positions in the index.
+// The columns in the same position are left in the order of the fields.
+func sortIndexColumns(indexes []*index, fields []*field) {
+	m := make(map[string]*field, len(fields))
+	for _, f := range fields {
+		m[f.Column] = f
+	}
+	for _, idx := range indexes {
+		idx := idx
+		sort.SliceStable(idx.Columns, func(i, j int) bool {
+			return m[idx.Columns[i]].IndexPosition(idx.Name) < m[idx.Columns[j]].IndexPosition(idx.Name)
+		})
+	}
+}
+
 type modifiedField struct {
 	old *field
 	new *field
@@ -481,12 +1476,13 @@ func (f *modifiedField) IsModified() bool {
 }
 
 func makeAlterTableFields(oldFields, newFields []*field) (fields []modifiedField) {
-	oldTable := make(map[string]*field, len(oldFields))
+	// Each field is looked up by both the column name and the field name.
+	oldTable := make(map[string]*field, len(oldFields)*2)
 	for _, f := range oldFields {
 		oldTable[f.Column] = f
 		oldTable[f.Name] = f
 	}
-	newTable := make(map[string]*field, len(newFields))
+	newTable := make(map[string]*field, len(newFields)*2)
 	for _, f := range newFields {
 		newTable[f.Column] = f
 		newTable[f.Name] = f
@@ -515,41 +1511,127 @@ func makeAlterTableFields(oldFields, newFields []*field) (fields []modifiedField
 }
 
 // Fprint generates Go's structs from database schema and writes to output.
-func Fprint(output io.Writer, d dialect.Dialect) error {
+//
+// By default, Fprint writes only the import declaration and the type declarations.
+// If WithPackageName option is given, Fprint also writes the package clause, so the output can be compiled as is.
+// WithHeader and WithImports options add the header comment and the extra imports.
+func Fprint(output io.Writer, d dialect.Dialect, opts ...Option) error {
+	o := newOption(opts...)
 	tableMap, err := getTableMap(d)
 	if err != nil {
 		return err
 	}
-	pkgMap := map[string]struct{}{}
-	for _, schemas := range tableMap {
-		for _, schema := range schemas {
-			if pkg := d.ImportPackage(schema); pkg != "" {
-				pkgMap[pkg] = struct{}{}
-			}
-		}
+	if err := o.filterTableMap(tableMap); err != nil {
+		return err
 	}
-	if len(pkgMap) != 0 {
-		pkgs := make([]string, 0, len(pkgMap))
-		for pkg := range pkgMap {
-			pkgs = append(pkgs, pkg)
-		}
-		sort.Strings(pkgs)
-		if err := fprintln(output, importAST(pkgs)); err != nil {
-			return err
-		}
+	if err := o.filterViews(d, tableMap); err != nil {
+		return err
 	}
+	o.filterUnsupportedTypes(d, tableMap)
 	names := make([]string, 0, len(tableMap))
 	for name := range tableMap {
 		names = append(names, name)
 	}
 	sort.Strings(names)
-	for _, name := range names {
-		s, err := makeStructAST(d, name, tableMap[name])
+	if o.header != "" {
+		fmt.Fprintf(output, "%s\n", commentLines(o.header))
+	}
+	if o.packageName != "" {
+		fmt.Fprintf(output, "package %s\n\n", o.packageName)
+	}
+	comments, err := tableComments(d)
+	if err != nil {
+		return err
+	}
+	return fprintTables(output, d, tableMap, comments, names, o)
+}
+
+// tableComments returns the comments of the tables if d supports it.
+func tableComments(d dialect.Dialect) (map[string]string, error) {
+	if c, ok := d.(dialect.TableCommenter); ok {
+		return c.TableComments()
+	}
+	return nil, nil
+}
+
+// commentLines makes each line of s a line comment unless it is already.
+func commentLines(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "//") {
+			lines[i] = strings.TrimRight("// "+line, " ")
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// fprintTables writes the import declaration and Go's structs of the tables that have names.
+// comments are written as the doc comments of the structs.
+// The declarations of the tables are generated concurrently, and written in order of names.
+func fprintTables(output io.Writer, d dialect.Dialect, tableMap map[string][]dialect.ColumnSchema, comments map[string]string, names []string, o *option) error {
+	bufs := make([]bytes.Buffer, len(names))
+	if err := parallelDo(len(names), o.concurrency(), func(i int) error {
+		return fprintTable(&bufs[i], d, names[i], comments[names[i]], tableMap[names[i]], o)
+	}); err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	for i := range bufs {
+		bufs[i].WriteTo(&body)
+	}
+	pkgs, err := importPackages(d, tableMap, names, body.Bytes(), o)
+	if err != nil {
+		return err
+	}
+	fprintImports(output, pkgs)
+	_, err = body.WriteTo(output)
+	return err
+}
+
+// fprintTable writes the declarations of the table such as Go's struct and the constants.
+func fprintTable(output io.Writer, d dialect.Dialect, name, comment string, schemas []dialect.ColumnSchema, o *option) error {
+	if o.template != nil {
+		data, err := newTemplateData(d, name, comment, schemas, o)
 		if err != nil {
 			return err
 		}
-		fmt.Fprintln(output, commentPrefix+marker)
-		if err := fprintln(output, s); err != nil {
+		return o.template.Execute(output, data)
+	}
+	s, err := makeStructAST(d, name, schemas, o)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(output, o.annotation(name))
+	if comment != "" {
+		fmt.Fprint(output, commentLines(comment))
+	}
+	if err := fprintln(output, s); err != nil {
+		return err
+	}
+	for _, e := range o.enumTypes(name, schemas) {
+		for _, decl := range enumDeclsAST(e) {
+			if err := fprintln(output, decl); err != nil {
+				return err
+			}
+		}
+	}
+	if o.tableNameConstant {
+		if err := fprintln(output, tableNameConstantAST(name, o.structName(name))); err != nil {
+			return err
+		}
+	}
+	if o.tableNameMethod {
+		if err := fprintln(output, tableNameMethodAST(name, o.structName(name))); err != nil {
+			return err
+		}
+	}
+	if o.columnConstants {
+		if err := fprintln(output, columnConstantsAST(o.structName(name), schemas)); err != nil {
+			return err
+		}
+	}
+	if o.crud && !o.isView(name) {
+		if err := fprintCRUD(output, d, name, schemas, o); err != nil {
 			return err
 		}
 	}
@@ -566,9 +1648,22 @@ const (
 	tagType          = "type"
 	tagNull          = "null"
 	tagExtra         = "extra"
+	tagBackfill      = "backfill"
+	tagForeignKey    = "fk"
 	tagIgnore        = "-"
 )
 
+// isBookkeepingTable reports whether the table named name is maintained by migu itself.
+// The default history and audit tables are always treated as such, even if the current options don't use them,
+// so that the tables created by an earlier run are never dropped.
+func isBookkeepingTable(name string, opt *option) bool {
+	switch name {
+	case DefaultHistoryTable, DefaultAuditTable, opt.historyTable, opt.auditTable:
+		return name != ""
+	}
+	return isArchiveTable(name)
+}
+
 func getTableMap(d dialect.Dialect, tables ...string) (map[string][]dialect.ColumnSchema, error) {
 	schemas, err := d.ColumnSchema(tables...)
 	if err != nil {
@@ -590,12 +1685,15 @@ func fprintln(output io.Writer, decl ast.Decl) error {
 }
 
 type structAST struct {
+	TypeName   string
 	StructType *ast.StructType
 	Annotation *annotation
+
+	// Fset is the file set that has the positions of StructType.
+	Fset *token.FileSet
 }
 
-func makeStructASTMap(filename string, src interface{}) (map[string]*structAST, error) {
-	fset := token.NewFileSet()
+func makeStructASTMap(fset *token.FileSet, filename string, src interface{}) (map[string]*structAST, error) {
 	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
 	if err != nil {
 		return nil, err
@@ -603,14 +1701,7 @@ func makeStructASTMap(filename string, src interface{}) (map[string]*structAST,
 	structASTMap := map[string]*structAST{}
 	for _, decl := range f.Decls {
 		d, ok := decl.(*ast.GenDecl)
-		if !ok || d.Tok != token.TYPE || d.Doc == nil {
-			continue
-		}
-		annotation, err := parseAnnotation(d.Doc)
-		if err != nil {
-			return nil, err
-		}
-		if annotation == nil {
+		if !ok || d.Tok != token.TYPE {
 			continue
 		}
 		for _, spec := range d.Specs {
@@ -622,14 +1713,21 @@ func makeStructASTMap(filename string, src interface{}) (map[string]*structAST,
 			if !ok {
 				continue
 			}
+			annotation, err := parseSpecAnnotation(fset, d, s)
+			if err != nil {
+				return nil, err
+			}
+			if annotation == nil || annotation.View {
+				continue
+			}
 			st := &structAST{
+				TypeName:   s.Name.Name,
 				StructType: t,
 				Annotation: annotation,
+				Fset:       fset,
 			}
-			if annotation.Table != "" {
-				structASTMap[annotation.Table] = st
-			} else {
-				structASTMap[stringutil.ToSnakeCase(s.Name.Name)] = st
+			for _, name := range annotation.tableNames(s.Name.Name) {
+				structASTMap[name] = st
 			}
 		}
 	}
@@ -654,6 +1752,16 @@ func detectTypeName(n ast.Node) (string, error) {
 			return "", err
 		}
 		return "*" + name, nil
+	case *ast.IndexExpr:
+		name, err := detectTypeName(t.X)
+		if err != nil {
+			return "", err
+		}
+		index, err := detectTypeName(t.Index)
+		if err != nil {
+			return "", err
+		}
+		return name + "[" + index + "]", nil
 	case *ast.ArrayType:
 		name, err := detectTypeName(t.Elt)
 		if err != nil {
@@ -665,25 +1773,25 @@ func detectTypeName(n ast.Node) (string, error) {
 	}
 }
 
-func importAST(pkgs []string) ast.Decl {
-	decl := &ast.GenDecl{
-		Tok: token.IMPORT,
+// structFieldAST returns the field of Go's struct for the column with the modifications by the options.
+func (o *option) structFieldAST(d dialect.Dialect, schema dialect.ColumnSchema, reordered map[string]bool) (*ast.Field, error) {
+	f, err := fieldAST(d, schema, reordered)
+	if err != nil {
+		return nil, err
 	}
-	for _, pkg := range pkgs {
-		decl.Specs = append(decl.Specs, &ast.ImportSpec{
-			Path: &ast.BasicLit{
-				Kind:  token.STRING,
-				Value: fmt.Sprintf(`"%s"`, pkg),
-			},
-		})
+	o.setNullableType(d, f, schema)
+	if e := o.enumTypeOf(schema.TableName(), schema); e != nil {
+		setEnumType(f, e)
 	}
-	return decl
+	o.addFieldTags(f, schema.ColumnName())
+	return f, nil
 }
 
-func makeStructAST(d dialect.Dialect, name string, schemas []dialect.ColumnSchema) (ast.Decl, error) {
+func makeStructAST(d dialect.Dialect, name string, schemas []dialect.ColumnSchema, o *option) (ast.Decl, error) {
 	var fields []*ast.Field
+	reordered := reorderedIndexes(schemas)
 	for _, schema := range schemas {
-		f, err := fieldAST(d, schema)
+		f, err := o.structFieldAST(d, schema, reordered)
 		if err != nil {
 			return nil, err
 		}
@@ -693,7 +1801,7 @@ func makeStructAST(d dialect.Dialect, name string, schemas []dialect.ColumnSchem
 		Tok: token.TYPE,
 		Specs: []ast.Spec{
 			&ast.TypeSpec{
-				Name: ast.NewIdent(stringutil.ToUpperCamelCase(name)),
+				Name: ast.NewIdent(o.structName(name)),
 				Type: &ast.StructType{
 					Fields: &ast.FieldList{
 						List: fields,
@@ -704,70 +1812,157 @@ func makeStructAST(d dialect.Dialect, name string, schemas []dialect.ColumnSchem
 	}, nil
 }
 
+// tableNameConstantAST returns the constant declaration of the table name. (e.g. const TableUser = "user")
+func tableNameConstantAST(name, structName string) ast.Decl {
+	return &ast.GenDecl{
+		Tok: token.CONST,
+		Specs: []ast.Spec{
+			&ast.ValueSpec{
+				Names: []*ast.Ident{
+					ast.NewIdent("Table" + structName),
+				},
+				Values: []ast.Expr{
+					&ast.BasicLit{
+						Kind:  token.STRING,
+						Value: strconv.Quote(name),
+					},
+				},
+			},
+		},
+	}
+}
+
+// tableNameMethodAST returns the TableName method declaration of the struct for the table.
+func tableNameMethodAST(name, structName string) ast.Decl {
+	return &ast.FuncDecl{
+		Recv: &ast.FieldList{
+			List: []*ast.Field{
+				{Type: ast.NewIdent(structName)},
+			},
+		},
+		Name: ast.NewIdent("TableName"),
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{},
+			Results: &ast.FieldList{
+				List: []*ast.Field{
+					{Type: ast.NewIdent("string")},
+				},
+			},
+		},
+		Body: &ast.BlockStmt{
+			List: []ast.Stmt{
+				&ast.ReturnStmt{
+					Results: []ast.Expr{
+						&ast.BasicLit{
+							Kind:  token.STRING,
+							Value: strconv.Quote(name),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// columnConstantsAST returns the constant declaration of the column names of the table.
+// (e.g. const UserColumnEmail = "email")
+func columnConstantsAST(structName string, schemas []dialect.ColumnSchema) ast.Decl {
+	decl := &ast.GenDecl{
+		Tok:    token.CONST,
+		Lparen: 1,
+	}
+	prefix := structName + "Column"
+	for _, schema := range schemas {
+		decl.Specs = append(decl.Specs, &ast.ValueSpec{
+			Names: []*ast.Ident{
+				ast.NewIdent(prefix + stringutil.ToUpperCamelCase(schema.ColumnName())),
+			},
+			Values: []ast.Expr{
+				&ast.BasicLit{
+					Kind:  token.STRING,
+					Value: strconv.Quote(schema.ColumnName()),
+				},
+			},
+		})
+	}
+	return decl
+}
+
 func parseStructTag(d dialect.Dialect, f *field, tag reflect.StructTag) error {
 	migu := tag.Get("migu")
 	if migu == "" {
 		return nil
 	}
-	scanner := bufio.NewScanner(strings.NewReader(migu))
-	scanner.Split(tagOptionSplit)
-	for scanner.Scan() {
-		opt := scanner.Text()
-		optval := strings.SplitN(opt, ":", 2)
-		switch optval[0] {
+	for _, opt := range splitTagOptions(migu) {
+		key, value, hasValue := opt, "", false
+		if i := strings.IndexByte(opt, ':'); i >= 0 {
+			key, value, hasValue = opt[:i], opt[i+1:], true
+		}
+		switch key {
 		case tagDefault:
-			if len(optval) > 1 {
-				f.Default = optval[1]
+			if hasValue {
+				f.Default = value
 			}
 		case tagPrimaryKey:
 			f.PrimaryKey = true
 		case tagAutoIncrement:
 			f.AutoIncrement = true
 		case tagIndex:
-			if len(optval) == 2 {
-				f.RawIndexes = append(f.RawIndexes, optval[1])
-			} else {
-				f.RawIndexes = append(f.RawIndexes, "")
-			}
+			name, pos := splitIndexPosition(value)
+			f.RawIndexes = append(f.RawIndexes, name)
+			f.setIndexPosition(name, pos)
 		case tagUnique:
-			if len(optval) == 2 {
-				f.RawUniques = append(f.RawUniques, optval[1])
-			} else {
-				f.RawUniques = append(f.RawUniques, "")
-			}
+			name, pos := splitIndexPosition(value)
+			f.RawUniques = append(f.RawUniques, name)
+			f.setIndexPosition(name, pos)
 		case tagIgnore:
 			f.Ignore = true
 		case tagColumn:
-			if len(optval) < 2 {
+			if !hasValue {
 				return fmt.Errorf("`column` tag must specify the parameter")
 			}
-			f.Column = optval[1]
+			f.Column = value
 		case tagType:
-			if len(optval) < 2 {
+			if !hasValue {
 				return fmt.Errorf("`type` tag must specify the parameter")
 			}
-			f.Type = optval[1]
+			f.Type = value
 		case tagNull:
 			f.Nullable = true
 		case tagExtra:
-			if len(optval) < 2 {
+			if !hasValue {
 				return fmt.Errorf("`extra` tag must specify the parameter")
 			}
-			f.Extra = optval[1]
+			f.Extra = value
+		case tagBackfill:
+			if !hasValue {
+				return fmt.Errorf("`backfill` tag must specify the parameter")
+			}
+			f.Backfill = value
+		case tagForeignKey:
+			if !hasValue || strings.IndexByte(value, '.') < 1 || strings.HasSuffix(value, ".") {
+				return fmt.Errorf("`fk` tag must specify the parameter as <table>.<column>")
+			}
+			f.ForeignKey = value
 		default:
 			return fmt.Errorf("unknown option: `%s'", opt)
 		}
 	}
-	return scanner.Err()
+	return nil
 }
 
-func tagOptionSplit(data []byte, atEOF bool) (advance int, token []byte, err error) {
+// splitTagOptions splits the value of migu tag into the options by comma.
+// The commas in parentheses such as "type:decimal(10,2)" are not treated as the separators.
+func splitTagOptions(s string) []string {
+	opts := make([]string, 0, strings.Count(s, ",")+1)
 	var inParenthesis bool
-	for i := 0; i < len(data); i++ {
-		switch data[i] {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
 		case ',':
 			if !inParenthesis {
-				return i + 1, data[:i], nil
+				opts = append(opts, s[start:i])
+				start = i + 1
 			}
 		case '(':
 			inParenthesis = true
@@ -775,10 +1970,40 @@ func tagOptionSplit(data []byte, atEOF bool) (advance int, token []byte, err err
 			inParenthesis = false
 		}
 	}
-	return 0, data, bufio.ErrFinalToken
+	return append(opts, s[start:])
+}
+
+// columnIndexes returns all indexes that contain the column.
+// The indexes that have the same name in the multiple columns make up a composite index.
+func columnIndexes(schema dialect.ColumnSchema) []dialect.ColumnIndex {
+	if s, ok := schema.(dialect.MultiIndexColumnSchema); ok {
+		return s.Indexes()
+	}
+	if name, unique, ok := schema.Index(); ok {
+		return []dialect.ColumnIndex{{Name: name, Unique: unique}}
+	}
+	return nil
+}
+
+// reorderedIndexes returns the names of the indexes whose columns are not in the order of the columns of the table.
+// The columns of such indexes need the positions in the tags.
+func reorderedIndexes(schemas []dialect.ColumnSchema) map[string]bool {
+	reordered := map[string]bool{}
+	last := map[string]int{}
+	for _, schema := range schemas {
+		for _, index := range columnIndexes(schema) {
+			if index.Position < last[index.Name] {
+				reordered[index.Name] = true
+			}
+			last[index.Name] = index.Position
+		}
+	}
+	return reordered
 }
 
-func fieldAST(d dialect.Dialect, schema dialect.ColumnSchema) (*ast.Field, error) {
+// fieldAST returns the field of Go's struct for the column.
+// The columns of the indexes in reordered have the positions in the index tags.
+func fieldAST(d dialect.Dialect, schema dialect.ColumnSchema, reordered map[string]bool) (*ast.Field, error) {
 	field := &ast.Field{
 		Names: []*ast.Ident{
 			ast.NewIdent(stringutil.ToUpperCamelCase(schema.ColumnName())),
@@ -796,17 +2021,25 @@ func fieldAST(d dialect.Dialect, schema dialect.ColumnSchema) (*ast.Field, error
 	if schema.IsAutoIncrement() {
 		tags = append(tags, tagAutoIncrement)
 	}
-	if v, unique, ok := schema.Index(); ok {
+	for _, index := range columnIndexes(schema) {
 		var tag string
-		if unique {
+		if index.Unique {
 			tag = tagUnique
 		} else {
 			tag = tagIndex
 		}
-		if v == schema.ColumnName() {
+		switch {
+		case reordered[index.Name] && index.Position > 0:
+			tags = append(tags, fmt.Sprintf("%s:%s:%d", tag, index.Name, index.Position))
+		case index.Name == schema.ColumnName():
 			tags = append(tags, tag)
-		} else {
-			tags = append(tags, fmt.Sprintf("%s:%s", tag, v))
+		default:
+			tags = append(tags, fmt.Sprintf("%s:%s", tag, index.Name))
+		}
+	}
+	if s, ok := schema.(dialect.ForeignKeyColumnSchema); ok {
+		if v, ok := s.ForeignKey(); ok {
+			tags = append(tags, tagForeignKey+":"+v)
 		}
 	}
 	if schema.IsNullable() {
@@ -0,0 +1,35 @@
+// Package migutest provides the helpers for the tests that guarantee the database schema is synchronized with Go's structs.
+package migutest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naoina/migu"
+	"github.com/naoina/migu/dialect"
+)
+
+// AssertSynced reports the test failure with the pending SQLs if the database schema differs from Go's structs.
+// Go's structs are read from filename in the same way as migu.Diff reads it with nil src, so filename can be a directory.
+// It returns whether the database schema is synchronized.
+func AssertSynced(t testing.TB, d dialect.Dialect, filename string, opts ...migu.Option) bool {
+	t.Helper()
+	sqls, err := migu.Diff(d, filename, nil, opts...)
+	if err != nil {
+		t.Errorf("migutest: %v", err)
+		return false
+	}
+	if len(sqls) > 0 {
+		t.Errorf("migutest: the database schema is not synchronized with %s. pending SQLs:\n%s", filename, strings.Join(sqls, ";\n")+";")
+		return false
+	}
+	return true
+}
+
+// RequireSynced is like AssertSynced, but stops the test by t.FailNow if the database schema is not synchronized.
+func RequireSynced(t testing.TB, d dialect.Dialect, filename string, opts ...migu.Option) {
+	t.Helper()
+	if !AssertSynced(t, d, filename, opts...) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,69 @@
+package migutest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/naoina/migu"
+	"github.com/naoina/migu/dialect"
+)
+
+// NewMySQLDB creates the scratch database that has a unique name on the MySQL server of dsn,
+// synchronizes it with Go's structs in filename by migu.Sync, and returns the connection to it.
+// The database is dropped when the test and all its subtests complete, so the parallel tests can have their own databases.
+//
+// The database name of dsn is ignored, and the user of dsn must have the privileges to create and drop the databases.
+// Go's structs are read from filename in the same way as AssertSynced.
+func NewMySQLDB(t testing.TB, dsn string, filename string, opts ...migu.Option) *sql.DB {
+	t.Helper()
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("migutest: %v", err)
+	}
+	cfg.DBName = ""
+	server, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatalf("migutest: %v", err)
+	}
+	name, err := scratchDatabaseName()
+	if err != nil {
+		server.Close()
+		t.Fatalf("migutest: %v", err)
+	}
+	quoted := dialect.NewMySQL(nil).Quote(name)
+	if _, err := server.Exec("CREATE DATABASE " + quoted); err != nil {
+		server.Close()
+		t.Fatalf("migutest: %v", err)
+	}
+	cfg.DBName = name
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	t.Cleanup(func() {
+		if db != nil {
+			db.Close()
+		}
+		if _, err := server.Exec("DROP DATABASE IF EXISTS " + quoted); err != nil {
+			t.Errorf("migutest: %v", err)
+		}
+		server.Close()
+	})
+	if err != nil {
+		t.Fatalf("migutest: %v", err)
+	}
+	if err := migu.Sync(dialect.NewMySQL(db), filename, nil, opts...); err != nil {
+		t.Fatalf("migutest: %v", err)
+	}
+	return db
+}
+
+// scratchDatabaseName returns the unique name of the scratch database.
+func scratchDatabaseName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("migutest_%s", hex.EncodeToString(b)), nil
+}
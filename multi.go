@@ -0,0 +1,100 @@
+package migu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// Target is a database that is synchronized by SyncTargets.
+type Target struct {
+	// Name is the name of the target that is used in the results and the errors, such as the name of the shard.
+	Name string
+
+	Dialect dialect.Dialect
+}
+
+// TargetResult is the result of the synchronization of a target.
+type TargetResult struct {
+	Target Target
+
+	// Report is the report of the synchronization. It may be nil if the synchronization failed before executing the statements.
+	Report *Report
+
+	// Err is the error of the synchronization, or nil if it succeeded.
+	Err error
+}
+
+// TargetError is the error of the synchronization of a target.
+type TargetError struct {
+	Name string
+	Err  error
+}
+
+func (e *TargetError) Error() string {
+	return fmt.Sprintf("migu: %s: %v", e.Name, e.Err)
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// TargetErrors is a list of *TargetError.
+type TargetErrors []*TargetError
+
+func (e TargetErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// SyncTargets synchronizes each of targets with Go's structs in order, such as the shards or the per-tenant databases.
+// Go's structs are read once in the same way as Sync reads filename and src, and the plan is computed per target
+// since the schemas of the targets may differ.
+//
+// SyncTargets stops at the first target that fails unless WithContinueOnError option is given.
+// It returns the results of the targets that were synchronized, and TargetErrors if any of them failed.
+func SyncTargets(targets []Target, filename string, src interface{}, opts ...Option) ([]*TargetResult, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := loadSeeds(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	var (
+		results []*TargetResult
+		errs    TargetErrors
+	)
+	for _, target := range targets {
+		report, err := syncStructASTMap(target.Dialect, structASTMap, seeds, o)
+		results = append(results, &TargetResult{
+			Target: target,
+			Report: report,
+			Err:    err,
+		})
+		if err == nil {
+			continue
+		}
+		errs = append(errs, &TargetError{
+			Name: target.Name,
+			Err:  err,
+		})
+		if !o.continueOnError {
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
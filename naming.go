@@ -0,0 +1,66 @@
+package migu
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+)
+
+// structName returns the name of Go's struct for the table.
+// The prefix given by WithTrimTablePrefix is removed, and the name is singularized if WithSingularStructNames is given.
+func (o *option) structName(table string) string {
+	name := table
+	for _, prefix := range o.trimTablePrefixes {
+		if strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			name = name[len(prefix):]
+			break
+		}
+	}
+	if o.singularStructNames {
+		i := strings.LastIndexByte(name, '_') + 1
+		name = name[:i] + singularize(name[i:])
+	}
+	return stringutil.ToUpperCamelCase(name)
+}
+
+// annotation returns the annotation comment of Go's struct for the table.
+// The table name is given explicitly if it cannot be derived from the struct name.
+func (o *option) annotation(table string) string {
+	a := commentPrefix + marker
+	if stringutil.ToSnakeCase(o.structName(table)) != table {
+		a += " table:" + strconv.Quote(table)
+	}
+	if o.isView(table) {
+		a += ` view:"true"`
+	}
+	return a
+}
+
+var irregularPlurals = map[string]string{
+	"people":   "person",
+	"men":      "man",
+	"women":    "woman",
+	"children": "child",
+}
+
+// singularize returns the singular form of the English noun s.
+// It handles only the common rules, and returns s as it is if s does not look like plural.
+func singularize(s string) string {
+	lower := strings.ToLower(s)
+	if singular, ok := irregularPlurals[lower]; ok {
+		return s[:len(s)-len(lower)] + singular
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "ches"),
+		strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return s
+	case strings.HasSuffix(lower, "s") && len(s) > 1:
+		return s[:len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+package migu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// integerRanges is the range of the values of the signed integer types.
+// The unsigned types have the range from 0 to max*2+1.
+var integerRanges = map[string][2]int64{
+	"TINYINT":   {-1 << 7, 1<<7 - 1},
+	"SMALLINT":  {-1 << 15, 1<<15 - 1},
+	"MEDIUMINT": {-1 << 23, 1<<23 - 1},
+	"INT":       {-1 << 31, 1<<31 - 1},
+	"INTEGER":   {-1 << 31, 1<<31 - 1},
+}
+
+// lengthLimits is the maximum length in bytes of the text and binary types that have no size.
+var lengthLimits = map[string]int64{
+	"TINYTEXT":   1<<8 - 1,
+	"TEXT":       1<<16 - 1,
+	"MEDIUMTEXT": 1<<24 - 1,
+	"TINYBLOB":   1<<8 - 1,
+	"BLOB":       1<<16 - 1,
+	"MEDIUMBLOB": 1<<24 - 1,
+}
+
+// narrowingCondition returns the condition of the rows that would be truncated or rejected by changing oldField to newField.
+// It returns an empty string if there are no such rows or the condition cannot be determined.
+func narrowingCondition(d dialect.Dialect, oldField, newField *field) string {
+	column := d.Quote(newField.Column)
+	var conds []string
+	if oldField.Nullable && !newField.Nullable {
+		conds = append(conds, fmt.Sprintf("%s IS NULL", column))
+	}
+	if isNarrowingType(oldField.Type, newField.Type) {
+		if cond := typeNarrowingCondition(column, newField.Type); cond != "" {
+			conds = append(conds, cond)
+		}
+	}
+	return strings.Join(conds, " OR ")
+}
+
+func typeNarrowingCondition(column, newType string) string {
+	base, size, unsigned := splitColumnType(newType)
+	if r, ok := integerRanges[base]; ok {
+		if unsigned {
+			return fmt.Sprintf("%s < 0 OR %s > %d", column, column, uint64(r[1])*2+1)
+		}
+		return fmt.Sprintf("%s < %d OR %s > %d", column, r[0], column, r[1])
+	}
+	if _, ok := integerTypeRanks[base]; ok {
+		if unsigned {
+			return fmt.Sprintf("%s < 0", column)
+		}
+		return ""
+	}
+	if _, ok := textTypeRanks[base]; ok {
+		if size > 0 {
+			return fmt.Sprintf("CHAR_LENGTH(%s) > %d", column, size)
+		}
+	}
+	if _, ok := binaryTypeRanks[base]; ok {
+		if size > 0 {
+			return fmt.Sprintf("LENGTH(%s) > %d", column, size)
+		}
+	}
+	if limit, ok := lengthLimits[base]; ok {
+		return fmt.Sprintf("LENGTH(%s) > %d", column, limit)
+	}
+	return ""
+}
+
+// narrowingValidation returns the query that counts the rows that would be truncated by changing oldField to newField.
+func narrowingValidation(d dialect.Dialect, table string, oldField, newField *field) string {
+	cond := narrowingCondition(d, oldField, newField)
+	if cond == "" {
+		return ""
+	}
+	return fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", quoteTable(d, table), cond)
+}
+
+// TruncationError is the error returned by Sync when the data would be truncated by narrowing a column.
+type TruncationError struct {
+	// Operation is the operation that narrows the column.
+	Operation Operation
+
+	// Rows is the number of the rows that would be truncated.
+	Rows int64
+}
+
+func (e *TruncationError) Error() string {
+	return fmt.Sprintf("migu: %d rows of %s.%s would be truncated by: %s", e.Rows, e.Operation.Table, e.Operation.Column, e.Operation.SQL)
+}
+
+// validateNarrowing executes the validation queries of the operations that are not skipped.
+func validateNarrowing(d dialect.Dialect, ops []Operation, completed int, skipped map[int]SkipReason) error {
+	for i := completed; i < len(ops); i++ {
+		op := ops[i]
+		if _, ok := skipped[i]; ok || op.Validation == "" {
+			continue
+		}
+		c, ok := d.(dialect.RowCounter)
+		if !ok {
+			return fmt.Errorf("migu: the dialect does not support the validation of the data")
+		}
+		n, err := c.CountRows(op.Validation)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return &TruncationError{
+				Operation: op,
+				Rows:      n,
+			}
+		}
+	}
+	return nil
+}
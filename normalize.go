@@ -0,0 +1,96 @@
+package migu
+
+import (
+	"math/big"
+	"strings"
+)
+
+// numericTypes is the column types whose default values are compared as numbers.
+var numericTypes = map[string]struct{}{
+	"TINYINT":   {},
+	"SMALLINT":  {},
+	"MEDIUMINT": {},
+	"INT":       {},
+	"INTEGER":   {},
+	"BIGINT":    {},
+	"DECIMAL":   {},
+	"NUMERIC":   {},
+	"FLOAT":     {},
+	"DOUBLE":    {},
+	"REAL":      {},
+	"INT64":     {},
+	"FLOAT64":   {},
+}
+
+// normalizeDefault returns the default value of the column that has columnType in the canonical form.
+// It is used to compare the default value given by the struct field tag with the one retrieved from the database,
+// since the database reports it in a different form. (e.g. "'0'" and "0", "0" and "0.00", "now()" and "CURRENT_TIMESTAMP")
+func normalizeDefault(columnType, def string) string {
+	if def == "" {
+		return def
+	}
+	upper := strings.ToUpper(def)
+	switch strings.TrimSuffix(upper, "()") {
+	case "CURRENT_TIMESTAMP", "NOW", "LOCALTIME", "LOCALTIMESTAMP":
+		return "CURRENT_TIMESTAMP"
+	}
+	if strings.HasPrefix(upper, "CURRENT_TIMESTAMP(") || strings.HasPrefix(upper, "NOW(") {
+		return "CURRENT_TIMESTAMP" + upper[strings.IndexByte(upper, '('):]
+	}
+	if !isNumericType(columnType) {
+		return def
+	}
+	if len(def) > 1 && def[0] == '\'' && def[len(def)-1] == '\'' {
+		def = def[1 : len(def)-1]
+	}
+	switch strings.ToUpper(def) {
+	case "TRUE":
+		return "1"
+	case "FALSE":
+		return "0"
+	}
+	if r, ok := new(big.Rat).SetString(def); ok {
+		return r.RatString()
+	}
+	return def
+}
+
+// isNumericType reports whether the column type such as "DECIMAL(10,2) UNSIGNED" is numeric.
+func isNumericType(columnType string) bool {
+	typ := strings.ToUpper(columnType)
+	if i := strings.IndexAny(typ, "( "); i >= 0 {
+		typ = typ[:i]
+	}
+	_, ok := numericTypes[typ]
+	return ok
+}
+
+// integerTypes is the integer column types whose display widths are ignored on comparison.
+var integerTypes = map[string]struct{}{
+	"TINYINT":   {},
+	"SMALLINT":  {},
+	"MEDIUMINT": {},
+	"INT":       {},
+	"INTEGER":   {},
+	"BIGINT":    {},
+}
+
+// normalizeColumnType returns the column type in the canonical form for comparison.
+// Only the sizes of the size-bearing types such as VARCHAR(255) and DECIMAL(10,2) are significant,
+// so the display widths of the integer types are removed. (e.g. "INT(11) UNSIGNED" to "INT UNSIGNED")
+// TINYINT(1) is kept as it is because it is used as the boolean type.
+func normalizeColumnType(columnType string) string {
+	typ := strings.ToUpper(columnType)
+	start := strings.IndexByte(typ, '(')
+	if start < 0 || typ == "TINYINT(1)" || strings.HasPrefix(typ, "TINYINT(1) ") {
+		return typ
+	}
+	if _, ok := integerTypes[typ[:start]]; !ok {
+		return typ
+	}
+	end := strings.IndexByte(typ[start:], ')')
+	if end < 0 {
+		return typ
+	}
+	return typ[:start] + typ[start+end+1:]
+}
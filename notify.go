@@ -0,0 +1,115 @@
+package migu
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook posts the summary of the synchronization to URL in JSON.
+// Its Notify method is intended to be given to WithNotifier option.
+//
+// The summary has "text" field that describes the result in a line, so it can be posted to
+// the incoming webhooks of Slack as it is.
+type Webhook struct {
+	// URL is the URL of the webhook.
+	URL string
+
+	// Client is the HTTP client to post the summary.
+	// If nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// OnError is called if posting the summary fails.
+	// If nil, the errors are ignored.
+	OnError func(err error)
+}
+
+// WebhookSummary is the summary posted by Webhook.
+type WebhookSummary struct {
+	Text       string                     `json:"text"`
+	Success    bool                       `json:"success"`
+	Error      string                     `json:"error,omitempty"`
+	Duration   string                     `json:"duration"`
+	Statements []*WebhookSummaryStatement `json:"statements"`
+	Skipped    []*WebhookSummaryStatement `json:"skipped,omitempty"`
+}
+
+// WebhookSummaryStatement is the statement in WebhookSummary.
+type WebhookSummaryStatement struct {
+	Kind     string `json:"kind"`
+	Table    string `json:"table"`
+	SQL      string `json:"sql"`
+	Duration string `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Notify posts the summary of report and err.
+// Nothing is posted if Sync succeeded without executing or skipping any statements.
+func (w *Webhook) Notify(report *Report, err error) {
+	if err == nil && (report == nil || len(report.Executed)+len(report.Skipped) == 0) {
+		return
+	}
+	body, e := json.Marshal(newWebhookSummary(report, err))
+	if e != nil {
+		w.onError(e)
+		return
+	}
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, e := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if e != nil {
+		w.onError(e)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		w.onError(fmt.Errorf("migu: webhook responded with %s", resp.Status))
+	}
+}
+
+func (w *Webhook) onError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+func newWebhookSummary(report *Report, err error) *WebhookSummary {
+	if report == nil {
+		report = &Report{}
+	}
+	summary := &WebhookSummary{
+		Success:    err == nil,
+		Duration:   report.Duration().String(),
+		Statements: []*WebhookSummaryStatement{},
+	}
+	for _, s := range report.Executed {
+		summary.Statements = append(summary.Statements, &WebhookSummaryStatement{
+			Kind:     s.Operation.Kind.String(),
+			Table:    s.Operation.Table,
+			SQL:      s.Operation.SQL,
+			Duration: s.Duration.String(),
+		})
+	}
+	for _, s := range report.Skipped {
+		summary.Skipped = append(summary.Skipped, &WebhookSummaryStatement{
+			Kind:   s.Operation.Kind.String(),
+			Table:  s.Operation.Table,
+			SQL:    s.Operation.SQL,
+			Reason: s.Reason.String(),
+		})
+	}
+	if err != nil {
+		summary.Error = err.Error()
+		summary.Text = fmt.Sprintf("migu: migration failed after %d statement(s): %v", len(report.Executed), err)
+	} else {
+		summary.Text = fmt.Sprintf("migu: %d statement(s) executed in %v", len(report.Executed), report.Duration().Round(time.Millisecond))
+	}
+	if len(report.Skipped) > 0 {
+		summary.Text += fmt.Sprintf(", %d statement(s) skipped", len(report.Skipped))
+	}
+	return summary
+}
@@ -0,0 +1,90 @@
+package migu
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// NullableStyle represents the style of Go's types of the nullable columns generated by Fprint.
+type NullableStyle int
+
+const (
+	// NullableDefault uses the default nullable type of the dialect. (e.g. "*string", "*time.Time")
+	NullableDefault NullableStyle = iota
+
+	// NullablePointer uses the pointer types. (e.g. "*string", "*int")
+	NullablePointer
+
+	// NullableSQLNull uses the sql.Null* types of database/sql. (e.g. "sql.NullString")
+	// The pointer type is used if there is no sql.Null* type for the column.
+	NullableSQLNull
+
+	// NullableSQLNullGeneric uses the generic sql.Null[T] type of database/sql that requires Go 1.22 or later. (e.g. "sql.Null[string]")
+	NullableSQLNullGeneric
+)
+
+func (s NullableStyle) String() string {
+	switch s {
+	case NullableDefault:
+		return "default"
+	case NullablePointer:
+		return "pointer"
+	case NullableSQLNull:
+		return "sql-null"
+	case NullableSQLNullGeneric:
+		return "sql-null-generic"
+	}
+	return fmt.Sprintf("NullableStyle(%d)", int(s))
+}
+
+// ParseNullableStyle returns the NullableStyle from its name such as "pointer".
+func ParseNullableStyle(s string) (NullableStyle, error) {
+	for _, style := range []NullableStyle{NullableDefault, NullablePointer, NullableSQLNull, NullableSQLNullGeneric} {
+		if strings.EqualFold(s, style.String()) {
+			return style, nil
+		}
+	}
+	return NullableDefault, fmt.Errorf("migu: unknown nullable style: %s", s)
+}
+
+// sqlNullTypes maps Go's types to the sql.Null* types.
+var sqlNullTypes = map[string]string{
+	"string":    "sql.NullString",
+	"bool":      "sql.NullBool",
+	"uint8":     "sql.NullByte",
+	"byte":      "sql.NullByte",
+	"int16":     "sql.NullInt16",
+	"int32":     "sql.NullInt32",
+	"int":       "sql.NullInt32",
+	"int64":     "sql.NullInt64",
+	"float64":   "sql.NullFloat64",
+	"time.Time": "sql.NullTime",
+}
+
+// goType returns Go's type of the nullable column in the style.
+// typ is Go's type of the column when it is not nullable.
+func (s NullableStyle) goType(typ string) string {
+	if strings.HasPrefix(typ, "[]") || typ == "interface{}" {
+		return typ
+	}
+	switch s {
+	case NullableSQLNull:
+		if t, ok := sqlNullTypes[typ]; ok {
+			return t
+		}
+	case NullableSQLNullGeneric:
+		return "sql.Null[" + typ + "]"
+	}
+	return "*" + typ
+}
+
+// setNullableType replaces the type of f for the nullable column in the style given by WithNullableStyle.
+func (o *option) setNullableType(d dialect.Dialect, f *ast.Field, schema dialect.ColumnSchema) {
+	if o.nullableStyle == NullableDefault || !schema.IsNullable() {
+		return
+	}
+	f.Type = ast.NewIdent(o.nullableStyle.goType(d.GoType(schema.ColumnType(), false)))
+}
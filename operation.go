@@ -0,0 +1,152 @@
+package migu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// OperationKind represents the kind of an Operation.
+type OperationKind int
+
+const (
+	OperationCreateTable OperationKind = iota + 1
+	OperationDropTable
+	OperationAddColumn
+	OperationDropColumn
+	OperationModifyColumn
+	OperationModifyPrimaryKey
+	OperationCreateIndex
+	OperationDropIndex
+	OperationBackfill
+	OperationArchive
+	OperationSeed
+	OperationPruneSeed
+	OperationModifyComment
+	OperationModifyTableOptions
+)
+
+var operationKindNames = map[OperationKind]string{
+	OperationCreateTable:        "CREATE TABLE",
+	OperationDropTable:          "DROP TABLE",
+	OperationAddColumn:          "ADD COLUMN",
+	OperationDropColumn:         "DROP COLUMN",
+	OperationModifyColumn:       "MODIFY COLUMN",
+	OperationModifyPrimaryKey:   "MODIFY PRIMARY KEY",
+	OperationCreateIndex:        "CREATE INDEX",
+	OperationDropIndex:          "DROP INDEX",
+	OperationBackfill:           "BACKFILL",
+	OperationArchive:            "ARCHIVE",
+	OperationSeed:               "SEED",
+	OperationPruneSeed:          "PRUNE SEED",
+	OperationModifyComment:      "MODIFY COMMENT",
+	OperationModifyTableOptions: "MODIFY TABLE OPTIONS",
+}
+
+func (k OperationKind) String() string {
+	if s, ok := operationKindNames[k]; ok {
+		return s
+	}
+	return "UNKNOWN"
+}
+
+// Operation represents a single SQL statement for the schema migration.
+type Operation struct {
+	Kind OperationKind
+
+	// Table is the name of the table affected by the operation.
+	Table string
+
+	// Column is the name of the column affected by the operation.
+	// It is empty if the operation does not affect a particular column.
+	Column string
+
+	SQL string
+
+	// Safety is the safety class of the operation.
+	Safety Safety
+
+	// Validation is the query that counts the rows that would be truncated by the operation.
+	// It is set only if WithNarrowingValidation option is given.
+	Validation string
+
+	// Down is the SQLs to revert the operation.
+	// If the operation consists of the multiple statements, Down is set to the first one and the others have no Down.
+	Down []string
+}
+
+// IsDestructive returns whether the operation may lose the data on the database.
+func (op Operation) IsDestructive() bool {
+	switch op.Kind {
+	case OperationDropTable, OperationDropColumn, OperationModifyColumn, OperationArchive, OperationPruneSeed:
+		return true
+	}
+	return false
+}
+
+// Checksum returns the SHA-256 checksum of the SQL in hex.
+func (op Operation) Checksum() string {
+	sum := sha256.Sum256([]byte(op.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// planChecksum returns the SHA-256 checksum of the SQLs of ops in hex, which identifies the plan.
+func planChecksum(ops []Operation) string {
+	sqls := make([]string, len(ops))
+	for i, op := range ops {
+		sqls[i] = op.SQL
+	}
+	sum := sha256.Sum256([]byte(strings.Join(sqls, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// historyChecksum returns the SHA-256 checksum in hex that identifies the i-th operation of the plan.
+// The same statement in the different plans or at the different positions has the different checksum.
+func historyChecksum(plan string, i int) string {
+	sum := sha256.Sum256([]byte(plan + ":" + strconv.Itoa(i)))
+	return hex.EncodeToString(sum[:])
+}
+
+func newOperations(kind OperationKind, table, column string, sqls, down []string) []Operation {
+	ops := make([]Operation, len(sqls))
+	for i, sql := range sqls {
+		ops[i] = Operation{
+			Kind:   kind,
+			Table:  table,
+			Column: column,
+			SQL:    sql,
+			Safety: kind.defaultSafety(),
+		}
+	}
+	if len(ops) > 0 {
+		ops[0].Down = down
+	}
+	return ops
+}
+
+// DownSQL returns the SQLs to revert all of ops.
+// The SQLs are in reverse order of ops.
+func DownSQL(ops []Operation) []string {
+	var sqls []string
+	for i := len(ops) - 1; i >= 0; i-- {
+		sqls = append(sqls, ops[i].Down...)
+	}
+	return sqls
+}
+
+// operationSQLs returns the SQLs of ops that are formatted by the options.
+func (o *option) operationSQLs(ops []Operation) []string {
+	if len(ops) == 0 {
+		return nil
+	}
+	sqls := make([]string, len(ops))
+	for i, op := range ops {
+		if o.format.isDefault() {
+			sqls[i] = op.SQL
+		} else {
+			sqls[i] = o.format.format(op.SQL)
+		}
+	}
+	return sqls
+}
@@ -0,0 +1,614 @@
+package migu
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// Option configures settings for Sync and Diff.
+type Option func(*option)
+
+type option struct {
+	confirm        func(op Operation) bool
+	parallelism    int
+	lintSeverities map[string]Severity
+	format         sqlFormat
+	historyTable   string
+	backfills      map[string]string
+
+	auditTable    string
+	auditMetadata AuditMetadata
+
+	disallowedSafety map[Safety]struct{}
+
+	archive    bool
+	archivedAt time.Time
+
+	dropUnknownTables    bool
+	strict               bool
+	commentMode          CommentMode
+	identifierValidation bool
+	caseInsensitiveNames bool
+
+	skipUnsupportedTypes bool
+	unsupportedColumns   *[]*UnsupportedColumn
+
+	readOnlyDrift *[]Operation
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	multiStatements int
+	continueOnError bool
+
+	progress ProgressStore
+	metrics  Metrics
+	notifier func(report *Report, err error)
+	shadow   bool
+
+	ctx            context.Context
+	tracer         Tracer
+	traceRedaction bool
+	logger         Logger
+
+	schemaCache *SchemaCache
+
+	narrowingValidation bool
+	seedPrune           bool
+
+	packageName string
+	header      string
+	imports     []string
+	fieldTags   []fieldTag
+
+	includeTables []string
+	excludeTables []string
+
+	columnConstants   bool
+	tableNameConstant bool
+	tableNameMethod   bool
+
+	merge    bool
+	editable bool
+	template Template
+
+	nullableStyle NullableStyle
+
+	singularStructNames bool
+	trimTablePrefixes   []string
+
+	crud bool
+
+	viewMode ViewMode
+	views    map[string]struct{}
+}
+
+func newOption(opts ...Option) *option {
+	o := &option{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithConfirm sets the callback that is called by Sync before executing a destructive operation such as DROP TABLE, DROP COLUMN and MODIFY.
+// If confirm returns false, Sync skips the operation.
+func WithConfirm(confirm func(op Operation) bool) Option {
+	return func(o *option) {
+		o.confirm = confirm
+	}
+}
+
+// WithParallelism sets the maximum number of tables that are made from Go's structs and compared concurrently by Diff,
+// and generated concurrently by Fprint and FprintDir.
+// The default is the value of runtime.GOMAXPROCS(0).
+func WithParallelism(n int) Option {
+	return func(o *option) {
+		o.parallelism = n
+	}
+}
+
+// WithRetry makes Sync retry a statement up to maxRetries times when it fails with a retryable error such as a lock wait timeout or a deadlock.
+// Sync waits for backoff before the first retry, and the wait is doubled on each retry.
+// WithRetry has no effect if the dialect does not implement dialect.Retryable.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(o *option) {
+		o.maxRetries = maxRetries
+		o.retryBackoff = backoff
+	}
+}
+
+// WithMultiStatements makes Sync execute up to size statements in a single call to cut the round trips of the many small statements.
+// Sync falls back to executing the statements one by one if the dialect does not implement dialect.MultiStatementExecer,
+// or the connection does not permit the multiple statements. (e.g. MySQL without multiStatements=true in DSN)
+//
+// The statements that are executed together are not retried by WithRetry. If one of them fails,
+// the statements before it are recorded as applied, and ExecError reports the failed one.
+func WithMultiStatements(size int) Option {
+	return func(o *option) {
+		o.multiStatements = size
+	}
+}
+
+// WithContinueOnError makes SyncTargets continue to synchronize the remaining targets after a target fails.
+func WithContinueOnError() Option {
+	return func(o *option) {
+		o.continueOnError = true
+	}
+}
+
+// WithProgressStore makes Sync record the progress into store, and resume the interrupted synchronization if store has the progress.
+// See ProgressStore for details.
+func WithProgressStore(store ProgressStore) Option {
+	return func(o *option) {
+		o.progress = store
+	}
+}
+
+// WithMetrics makes Sync and CheckDrift report the executed statements and the detected drifts to metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *option) {
+		o.metrics = metrics
+	}
+}
+
+// WithNotifier makes Sync call notifier with the report and the error after the synchronization completes,
+// whether it succeeded or not. The report is nil if Sync failed before executing the statements.
+// (*Webhook).Notify can be used to post the summary to a webhook such as Slack.
+func WithNotifier(notifier func(report *Report, err error)) Option {
+	return func(o *option) {
+		o.notifier = notifier
+	}
+}
+
+// WithContext sets the context of Sync, Diff and Plan.
+// Sync stops before the next statement when ctx is done, and the spans of WithTracer are started as the children of the span in ctx.
+func WithContext(ctx context.Context) Option {
+	return func(o *option) {
+		o.ctx = ctx
+	}
+}
+
+// WithTracer makes Sync, Diff and Plan trace the introspection, the planning and each executed statement by tracer.
+// The SQLs of the executed statements are added to the spans as the attributes unless WithTraceRedaction option is given.
+func WithTracer(tracer Tracer) Option {
+	return func(o *option) {
+		o.tracer = tracer
+	}
+}
+
+// WithLogger makes Sync, Diff and Plan log the introspection, the planning and each executed statement by logger.
+// NewTextLogger and NewJSONLogger return the Logger that writes the entries to io.Writer.
+func WithLogger(logger Logger) Option {
+	return func(o *option) {
+		o.logger = logger
+	}
+}
+
+// WithTraceRedaction omits the SQLs from the attributes of the spans of WithTracer,
+// because the SQLs such as the seed data and the backfill may contain the sensitive values.
+func WithTraceRedaction() Option {
+	return func(o *option) {
+		o.traceRedaction = true
+	}
+}
+
+// WithSchemaCache makes Diff, Sync and Plan retrieve the schema of the database through cache.
+// See SchemaCache for details.
+func WithSchemaCache(cache *SchemaCache) Option {
+	return func(o *option) {
+		o.schemaCache = cache
+	}
+}
+
+// WithShadowValidation makes Sync apply the whole plan to the shadow database that is created from the current schema before touching the real database.
+// If any statement fails on the shadow database, Sync returns the error without executing any statement on the real database.
+// The dialect must implement dialect.Shadower.
+func WithShadowValidation() Option {
+	return func(o *option) {
+		o.shadow = true
+	}
+}
+
+// WithNarrowingValidation makes Sync count the rows that would be truncated or rejected before narrowing a column
+// (e.g. VARCHAR(255) to VARCHAR(64), BIGINT to INT, or NULL to NOT NULL).
+// If there are such rows, Sync returns *TruncationError without executing any statement.
+// The dialect must implement dialect.RowCounter.
+func WithNarrowingValidation() Option {
+	return func(o *option) {
+		o.narrowingValidation = true
+	}
+}
+
+// WithSeedPrune makes Sync delete the rows of the seed tables that are not declared in the seed data.
+func WithSeedPrune() Option {
+	return func(o *option) {
+		o.seedPrune = true
+	}
+}
+
+// WithPackageName sets the package name of the code generated by Fprint and FprintDir.
+func WithPackageName(name string) Option {
+	return func(o *option) {
+		o.packageName = name
+	}
+}
+
+// WithHeader sets the header comment of the code generated by Fprint and FprintDir.
+// Each line of header is made a line comment unless it starts with "//".
+// e.g. "Code generated by migu. DO NOT EDIT."
+func WithHeader(header string) Option {
+	return func(o *option) {
+		o.header = header
+	}
+}
+
+// WithImports adds the import paths to the code generated by Fprint and FprintDir.
+// As with goimports, the package is imported only if the generated code refers to it, such as by WithTemplate,
+// so that the code can be compiled. The name of the package is assumed from the last element of the import path.
+func WithImports(pkgs ...string) Option {
+	return func(o *option) {
+		o.imports = append(o.imports, pkgs...)
+	}
+}
+
+// WithJSONTag makes Fprint and FprintDir add `json:"<name>"` tag to each field.
+// The name is made from the column name in style.
+func WithJSONTag(style NamingStyle) Option {
+	return func(o *option) {
+		o.fieldTags = append(o.fieldTags, fieldTag{
+			key:   "json",
+			value: style.name,
+		})
+	}
+}
+
+// WithDBTag makes Fprint and FprintDir add `db:"<name>"` tag for sqlx to each field.
+// The name is made from the column name in style.
+func WithDBTag(style NamingStyle) Option {
+	return func(o *option) {
+		o.fieldTags = append(o.fieldTags, fieldTag{
+			key:   "db",
+			value: style.name,
+		})
+	}
+}
+
+// WithGormTag makes Fprint and FprintDir add `gorm:"column:<column>"` tag to each field.
+func WithGormTag() Option {
+	return func(o *option) {
+		o.fieldTags = append(o.fieldTags, fieldTag{
+			key: "gorm",
+			value: func(column string) string {
+				return "column:" + column
+			},
+		})
+	}
+}
+
+// WithIncludeTables makes Fprint, FprintDir and DumpSQLDir output only the tables that match any of patterns,
+// and makes Diff and Sync compare only such tables.
+// Each pattern is a regular expression that must match the whole table name, so the plain table name can be used as is.
+func WithIncludeTables(patterns ...string) Option {
+	return func(o *option) {
+		o.includeTables = append(o.includeTables, patterns...)
+	}
+}
+
+// WithExcludeTables makes Fprint, FprintDir, DumpSQLDir, Diff and Sync skip the tables that match any of patterns.
+// The syntax of patterns is the same as WithIncludeTables. WithExcludeTables takes precedence over WithIncludeTables.
+func WithExcludeTables(patterns ...string) Option {
+	return func(o *option) {
+		o.excludeTables = append(o.excludeTables, patterns...)
+	}
+}
+
+// WithColumnConstants makes Fprint and FprintDir generate the constants of the column names for each table.
+// The name of the constant is "<Struct>Column<Field>". (e.g. const UserColumnEmail = "email")
+func WithColumnConstants() Option {
+	return func(o *option) {
+		o.columnConstants = true
+	}
+}
+
+// WithTableNameConstants makes Fprint and FprintDir generate the constant of the table name for each table.
+// The name of the constant is "Table<Struct>". (e.g. const TableUser = "user")
+func WithTableNameConstants() Option {
+	return func(o *option) {
+		o.tableNameConstant = true
+	}
+}
+
+// WithTableNameMethod makes Fprint and FprintDir generate TableName method that returns the table name for each struct.
+// The method satisfies the interface of some ORMs such as gorm.
+func WithTableNameMethod() Option {
+	return func(o *option) {
+		o.tableNameMethod = true
+	}
+}
+
+// WithMerge makes FprintDir merge Go's structs into the existing files instead of overwriting them.
+// Only the fields for the columns, their types and migu tags are updated.
+// The methods, the other declarations, the fields tagged `migu:"-"`, the other struct tags and the comments are preserved.
+// As with WithEditableFiles, the files are written without the header of the generated code and are never removed.
+func WithMerge() Option {
+	return func(o *option) {
+		o.merge = true
+	}
+}
+
+// WithEditableFiles makes FprintDir write the files without the header of the generated code,
+// so that the files are maintained by hand as the source of Go's structs for Sync.
+// Such files are never removed even if their tables no longer exist in the database.
+func WithEditableFiles() Option {
+	return func(o *option) {
+		o.editable = true
+	}
+}
+
+// WithTemplate makes Fprint and FprintDir render each table by tmpl instead of the default declarations.
+// tmpl is executed with *TemplateData. The imports and the package clause are still generated by Fprint.
+func WithTemplate(tmpl Template) Option {
+	return func(o *option) {
+		o.template = tmpl
+	}
+}
+
+// WithNullableStyle makes Fprint and FprintDir generate Go's types of the nullable columns in style.
+// By default, the default nullable type of the dialect is used.
+func WithNullableStyle(style NullableStyle) Option {
+	return func(o *option) {
+		o.nullableStyle = style
+	}
+}
+
+// WithSingularStructNames makes Fprint and FprintDir singularize the table names for the struct names. (e.g. "users" to User)
+// The table name is given by the annotation if it differs from the struct name.
+func WithSingularStructNames() Option {
+	return func(o *option) {
+		o.singularStructNames = true
+	}
+}
+
+// WithTrimTablePrefix makes Fprint and FprintDir remove the prefix from the table names for the struct names.
+// (e.g. "wp_users" to WpUsers by default, or to Users with WithTrimTablePrefix("wp_"))
+// The first matching prefix is removed.
+func WithTrimTablePrefix(prefixes ...string) Option {
+	return func(o *option) {
+		o.trimTablePrefixes = append(o.trimTablePrefixes, prefixes...)
+	}
+}
+
+// WithCRUD makes Fprint and FprintDir generate the CRUD helpers that use database/sql for each struct.
+// The helpers are Insert, Update and Delete methods and Select<Struct>ByPK function.
+// Update, Delete and Select<Struct>ByPK are generated only for the table that has the primary key.
+// The placeholders of the queries are "?" unless the dialect implements dialect.Placeholderer.
+func WithCRUD() Option {
+	return func(o *option) {
+		o.crud = true
+	}
+}
+
+// WithViewMode makes Fprint and FprintDir treat the views in the database according to mode.
+// By default, the views are generated in the same way as the tables.
+func WithViewMode(mode ViewMode) Option {
+	return func(o *option) {
+		o.viewMode = mode
+	}
+}
+
+// WithLintSeverity changes the severity of the lint rule that has id.
+// SeverityOff disables the rule.
+func WithLintSeverity(id string, severity Severity) Option {
+	return func(o *option) {
+		if o.lintSeverities == nil {
+			o.lintSeverities = map[string]Severity{}
+		}
+		o.lintSeverities[id] = severity
+	}
+}
+
+// WithKeywordCase sets the letter case of SQL keywords in the SQLs returned by Diff.
+// The default is KeywordUpper.
+func WithKeywordCase(c KeywordCase) Option {
+	return func(o *option) {
+		o.format.keywordCase = c
+	}
+}
+
+// WithSemicolon makes each SQL returned by Diff terminated by a semicolon.
+func WithSemicolon() Option {
+	return func(o *option) {
+		o.format.semicolon = true
+	}
+}
+
+// WithAlterSpecPerLine puts each specification of ALTER TABLE statements returned by Diff on its own line.
+func WithAlterSpecPerLine() Option {
+	return func(o *option) {
+		o.format.alterSpecLines = true
+	}
+}
+
+// WithMaxLineWidth wraps the lines of SQLs returned by Diff at width as possible.
+func WithMaxLineWidth(width int) Option {
+	return func(o *option) {
+		o.format.maxLineWidth = width
+	}
+}
+
+// DefaultHistoryTable is the name of the history table used by WithHistory.
+const DefaultHistoryTable = "migu_migrations"
+
+// WithHistory makes Sync record each applied statement into the history table named DefaultHistoryTable.
+// The statement is recorded with its position in the plan, and the statements of the same plan that have already been
+// recorded are skipped, such as when the interrupted plan is applied again. The same statement in another plan is executed.
+// The history is recorded in the same transaction as the statement if the dialect implements dialect.HistorySQLer.
+func WithHistory() Option {
+	return WithHistoryTable(DefaultHistoryTable)
+}
+
+// WithHistoryTable is like WithHistory, but uses the history table named table.
+func WithHistoryTable(table string) Option {
+	return func(o *option) {
+		o.historyTable = table
+	}
+}
+
+// WithAudit makes Sync append each applied statement with metadata into the audit table named DefaultAuditTable.
+// Unlike WithHistory, the audit table keeps all applied statements to answer who changed the schema and when.
+// The records can be retrieved by AuditLog.
+func WithAudit(metadata AuditMetadata) Option {
+	return WithAuditTable(DefaultAuditTable, metadata)
+}
+
+// WithAuditTable is like WithAudit, but uses the audit table named table.
+func WithAuditTable(table string, metadata AuditMetadata) Option {
+	return func(o *option) {
+		o.auditTable = table
+		o.auditMetadata = metadata
+	}
+}
+
+// WithBackfill sets the statement to fill the column of the table when the column is added as NOT NULL without default value.
+// Diff generates three steps for such a column: adding the column as nullable, executing statement, and then modifying the column to NOT NULL.
+//
+// The backfill can also be specified by `backfill` struct field tag with an expression of the column value.
+// WithBackfill takes precedence over the struct field tag.
+func WithBackfill(table, column, statement string) Option {
+	return func(o *option) {
+		if o.backfills == nil {
+			o.backfills = map[string]string{}
+		}
+		o.backfills[table+"."+column] = statement
+	}
+}
+
+// WithDisallowedSafety makes Diff and Sync return *SafetyError if the migration has any operations classified as classes.
+func WithDisallowedSafety(classes ...Safety) Option {
+	return func(o *option) {
+		if o.disallowedSafety == nil {
+			o.disallowedSafety = map[Safety]struct{}{}
+		}
+		for _, c := range classes {
+			o.disallowedSafety[c] = struct{}{}
+		}
+	}
+}
+
+// WithDropUnknownTables makes Diff and Sync drop the tables in the database that have no corresponding struct.
+// By default, only the tables of the structs are retrieved from the database, so that the introspection of
+// a huge database stays fast, and the other tables are left as they are.
+// The views are never dropped if the dialect implements dialect.ViewLister.
+func WithDropUnknownTables() Option {
+	return func(o *option) {
+		o.dropUnknownTables = true
+	}
+}
+
+// WithCommentMode makes Diff and Sync treat the columns that differ only in the comments according to mode.
+// By default, such columns are modified in the same way as the other differences.
+func WithCommentMode(mode CommentMode) Option {
+	return func(o *option) {
+		o.commentMode = mode
+	}
+}
+
+// WithIdentifierValidation makes Diff and Sync validate the names of the tables, the columns and the indexes of Go's structs
+// against the length limit and the reserved words of the database before generating SQLs.
+// The invalid names are reported as ValidationErrors that point to the structs and the struct fields.
+//
+// WithIdentifierValidation has no effect if the dialect does not implement dialect.IdentifierValidator.
+func WithIdentifierValidation() Option {
+	return func(o *option) {
+		o.identifierValidation = true
+	}
+}
+
+// WithCaseInsensitiveNames makes Diff and Sync match the names of the tables, the columns and the indexes of the database
+// with the ones of Go's structs case-insensitively, for the databases whose case sensitivity of the names depends on
+// the platform, such as MySQL with lower_case_table_names. The names of Go's structs are used in the generated SQLs.
+func WithCaseInsensitiveNames() Option {
+	return func(o *option) {
+		o.caseInsensitiveNames = true
+	}
+}
+
+// WithStrict makes Diff and Sync return AmbiguityErrors instead of silently choosing the column types if the mappings of
+// the struct fields are ambiguous or lossy, such as Go's int type whose size depends on the platform, float32 type mapped to DOUBLE,
+// Go's types that are not mapped to any column type, and the struct field tags that cannot be combined.
+// The ambiguous types can be resolved by the sized types such as int64, or by `type` tag.
+func WithStrict() Option {
+	return func(o *option) {
+		o.strict = true
+	}
+}
+
+// WithSkipUnsupportedTypes makes Fprint, FprintDir, Diff and Sync skip the columns of the database that have
+// the data types not supported by the dialect, instead of generating them as interface{} fields.
+// The tables that have only such columns are also skipped, and Diff and Sync never touch the skipped columns
+// even if the structs have the fields of them.
+//
+// The skipped columns are appended to *skipped as warnings if skipped is not nil.
+func WithSkipUnsupportedTypes(skipped *[]*UnsupportedColumn) Option {
+	return func(o *option) {
+		o.skipUnsupportedTypes = true
+		o.unsupportedColumns = skipped
+	}
+}
+
+// WithReadOnlyDrift makes Diff, Plan and Sync append the operations that would migrate the read-only tables to *drift.
+// The read-only tables are the tables of the structs that have "readonly" annotation. Their operations are never
+// generated regardless of this option, so that the tables owned by another system are only verified.
+func WithReadOnlyDrift(drift *[]Operation) Option {
+	return func(o *option) {
+		o.readOnlyDrift = drift
+	}
+}
+
+// WithArchive makes DROP TABLE to be renaming the table to "_migu_trash_<table>_<timestamp>",
+// and DROP COLUMN to be preceded by copying the data of the column with the primary key into "_migu_trash_<table>_<column>_<timestamp>" table.
+// The archived tables can be dropped by PurgeArchives later.
+// The name of the archive table that is longer than 64 characters is truncated and followed by the hash of the original names.
+// The archiving operations are destructive as well as DROP TABLE and DROP COLUMN.
+//
+// WithArchive has no effect if the dialect does not implement dialect.Archiver.
+func WithArchive() Option {
+	return func(o *option) {
+		o.archive = true
+	}
+}
+
+func (o *option) archiver(d dialect.Dialect) (dialect.Archiver, bool) {
+	if !o.archive {
+		return nil, false
+	}
+	a, ok := d.(dialect.Archiver)
+	return a, ok
+}
+
+// backfillSQL returns the statement to fill the column that is added as f.
+// It returns an empty string if the column does not need to be filled.
+func (o *option) backfillSQL(d dialect.Dialect, f *field) string {
+	if f.Nullable || f.Default != "" || f.AutoIncrement {
+		return ""
+	}
+	if stmt, ok := o.backfills[f.Table+"."+f.Column]; ok {
+		return stmt
+	}
+	if f.Backfill != "" {
+		return fmt.Sprintf("UPDATE %s SET %s = %s WHERE TRUE", quoteTable(d, f.Table), d.Quote(f.Column), f.Backfill)
+	}
+	return ""
+}
+
+func (o *option) concurrency() int {
+	if o.parallelism > 0 {
+		return o.parallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
@@ -0,0 +1,128 @@
+package migu
+
+import (
+	"context"
+	"io/fs"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// MigrationPlan is the set of the operations that are computed by Plan.
+// It can be inspected before it is applied to the database by Apply.
+type MigrationPlan struct {
+	// Operations is the operations of the plan in order of execution.
+	Operations []Operation
+
+	// Fingerprint is the fingerprint of the schema that is defined by Go's structs. See (*Schema).Fingerprint.
+	Fingerprint string
+
+	opt *option
+}
+
+// Plan computes the operations for schema synchronous between database and Go's struct without executing them.
+// Go's structs are read in the same way as Sync reads filename and src.
+// The options are kept in the plan and used by Apply as well.
+//
+// Plan and Apply is equivalent to Sync, but the database is introspected and the source is parsed only once,
+// so the plan that is shown to the user is exactly what is applied.
+func Plan(d dialect.Dialect, filename string, src interface{}, opts ...Option) (*MigrationPlan, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := loadSeeds(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	return planStructASTMap(o.context(), d, structASTMap, seeds, o)
+}
+
+// PlanFS is like Plan, but reads Go's structs from the files in fsys that match any of patterns in the same way as SyncFS.
+func PlanFS(d dialect.Dialect, fsys fs.FS, patterns []string, opts ...Option) (*MigrationPlan, error) {
+	structASTMap, err := loadStructASTMapFS(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := loadSeedsFS(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	return planStructASTMap(o.context(), d, structASTMap, seeds, o)
+}
+
+// planStructASTMap computes the operations of the schema and the seeds in "migu.Plan" span.
+func planStructASTMap(ctx context.Context, d dialect.Dialect, structASTMap map[string]*structAST, seeds []*seed, o *option) (plan *MigrationPlan, err error) {
+	ctx, end := o.startSpan(ctx, "migu.Plan")
+	defer func() {
+		end(err)
+	}()
+	structMap, err := makeTableMap(d, structASTMap, o)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := diff(ctx, d, structMap, o)
+	if err != nil {
+		return nil, err
+	}
+	seedOps, err := seedOperations(d, seeds, structASTMap, structMap, o)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationPlan{
+		Operations:  append(ops, seedOps...),
+		Fingerprint: newSchema(structMap).Fingerprint(),
+		opt:         o,
+	}, nil
+}
+
+// Apply executes the operations of the plan in the same way as SyncReport.
+// Apply stops before the next statement when ctx is done.
+// As with Sync, all statements are executed on a single database session if the dialect supports it.
+func (p *MigrationPlan) Apply(ctx context.Context, d dialect.Dialect) (report *Report, err error) {
+	ctx, end := p.opt.startSpan(ctx, "migu.Apply")
+	defer func() {
+		end(err)
+	}()
+	d, release, err := pinSession(ctx, d, p.opt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if e := release(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	return applyOperations(ctx, d, p.Operations, 0, p.Fingerprint, p.opt)
+}
+
+// SQL returns the SQLs of the plan.
+func (p *MigrationPlan) SQL() []string {
+	return p.opt.operationSQLs(p.Operations)
+}
+
+// DownSQL returns the SQLs to revert the plan.
+func (p *MigrationPlan) DownSQL() []string {
+	return DownSQL(p.Operations)
+}
+
+// Destructive returns the operations of the plan that may lose the data on the database.
+func (p *MigrationPlan) Destructive() []Operation {
+	var ops []Operation
+	for _, op := range p.Operations {
+		if op.IsDestructive() {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// IsEmpty returns whether the plan has no operations.
+func (p *MigrationPlan) IsEmpty() bool {
+	return len(p.Operations) == 0
+}
@@ -0,0 +1,77 @@
+package migu
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// Progress is the state of the synchronization that is in progress.
+type Progress struct {
+	// Operations is the migration plan of the synchronization.
+	Operations []Operation `json:"operations"`
+
+	// Completed is the number of the operations that have been completed from the beginning of Operations.
+	Completed int `json:"completed"`
+
+	// Fingerprint is the fingerprint of the schema that Operations was planned from. See (*Schema).Fingerprint.
+	// Sync refuses to resume the progress if Go's structs have been changed since then.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// ProgressStore is the interface to store the progress of Sync.
+//
+// Most DDL statements cause an implicit commit, so a failure in the middle of Sync leaves the database half-migrated.
+// If WithProgressStore is given, Sync records the progress into the store after each statement,
+// and the next Sync resumes the recorded plan from the failed statement instead of re-planning against the partially-changed schema.
+type ProgressStore interface {
+	// LoadProgress returns the progress that has been saved.
+	// It returns nil if there is no progress.
+	LoadProgress() (*Progress, error)
+
+	// SaveProgress saves the progress.
+	SaveProgress(progress *Progress) error
+
+	// ClearProgress removes the saved progress.
+	ClearProgress() error
+}
+
+// FileProgressStore is the ProgressStore that saves the progress into the file in JSON.
+type FileProgressStore string
+
+// LoadProgress implements ProgressStore.
+func (s FileProgressStore) LoadProgress() (*Progress, error) {
+	b, err := os.ReadFile(string(s))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var progress Progress
+	if err := json.Unmarshal(b, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// SaveProgress implements ProgressStore.
+func (s FileProgressStore) SaveProgress(progress *Progress) error {
+	b, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	tmp := string(s) + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(s))
+}
+
+// ClearProgress implements ProgressStore.
+func (s FileProgressStore) ClearProgress() error {
+	if err := os.Remove(string(s)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
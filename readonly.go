@@ -0,0 +1,25 @@
+package migu
+
+// excludeReadOnly returns ops without the operations of the read-only tables of oldMap and newMap.
+// The excluded operations are logged as the drift, and appended to the list of WithReadOnlyDrift.
+func (o *option) excludeReadOnly(ops []Operation, oldMap, newMap map[string]*table) []Operation {
+	isReadOnly := func(name string) bool {
+		if tbl := newMap[name]; tbl != nil && tbl.ReadOnly {
+			return true
+		}
+		tbl := oldMap[name]
+		return tbl != nil && tbl.ReadOnly
+	}
+	result := make([]Operation, 0, len(ops))
+	for _, op := range ops {
+		if !isReadOnly(op.Table) {
+			result = append(result, op)
+			continue
+		}
+		o.log(LogLevelWarn, "the read-only table differs from the struct", operationAttributes(op)...)
+		if o.readOnlyDrift != nil {
+			*o.readOnlyDrift = append(*o.readOnlyDrift, op)
+		}
+	}
+	return result
+}
@@ -0,0 +1,68 @@
+package migu
+
+import (
+	"time"
+)
+
+// SkipReason represents the reason why Sync skipped an operation.
+type SkipReason int
+
+const (
+	// SkipDeclined means that the operation was declined by the callback of WithConfirm.
+	SkipDeclined SkipReason = iota + 1
+
+	// SkipApplied means that the operation has already been applied according to the history table.
+	SkipApplied
+)
+
+func (r SkipReason) String() string {
+	switch r {
+	case SkipDeclined:
+		return "declined"
+	case SkipApplied:
+		return "already applied"
+	}
+	return "unknown"
+}
+
+// Report is the result of the synchronization by SyncReport.
+type Report struct {
+	// Executed is the statements that were executed successfully in order.
+	Executed []*ExecutedStatement
+
+	// Skipped is the statements that were not executed in order.
+	Skipped []*SkippedStatement
+}
+
+// ExecutedStatement is a statement that was executed by Sync.
+type ExecutedStatement struct {
+	Operation Operation
+	Duration  time.Duration
+
+	// RowsAffected is the number of rows affected by the statement.
+	// It is -1 if the dialect cannot report it.
+	RowsAffected int64
+}
+
+// SkippedStatement is a statement that was skipped by Sync.
+type SkippedStatement struct {
+	Operation Operation
+	Reason    SkipReason
+}
+
+// Duration returns the total duration of the executed statements.
+func (r *Report) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range r.Executed {
+		d += s.Duration
+	}
+	return d
+}
+
+func (r *Report) operations() []Operation {
+	ops := make([]Operation, len(r.Executed))
+	for i, s := range r.Executed {
+		ops[i] = s.Operation
+	}
+	return ops
+}
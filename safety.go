@@ -0,0 +1,243 @@
+package migu
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Safety represents the safety class of an Operation.
+type Safety int
+
+const (
+	// SafetySafe means the operation can be applied without affecting the existing data and queries.
+	SafetySafe Safety = iota
+
+	// SafetyLocking means the operation may lock or rebuild the table for a while.
+	SafetyLocking
+
+	// SafetyDestructive means the operation drops the table or the column with its data.
+	SafetyDestructive
+
+	// SafetyDataLossy means the operation may truncate or convert the existing data, such as narrowing the column type.
+	SafetyDataLossy
+)
+
+func (s Safety) String() string {
+	switch s {
+	case SafetySafe:
+		return "safe"
+	case SafetyLocking:
+		return "locking"
+	case SafetyDestructive:
+		return "destructive"
+	case SafetyDataLossy:
+		return "data-lossy"
+	}
+	return fmt.Sprintf("Safety(%d)", int(s))
+}
+
+// ParseSafety returns the Safety from its name such as "destructive".
+func ParseSafety(s string) (Safety, error) {
+	for _, safety := range []Safety{SafetySafe, SafetyLocking, SafetyDestructive, SafetyDataLossy} {
+		if strings.EqualFold(s, safety.String()) {
+			return safety, nil
+		}
+	}
+	return SafetySafe, fmt.Errorf("migu: unknown safety class: %s", s)
+}
+
+// SafetyError is the error returned when the migration has the operations whose safety class is disallowed by WithDisallowedSafety.
+type SafetyError struct {
+	Operations []Operation
+}
+
+func (e *SafetyError) Error() string {
+	msgs := make([]string, len(e.Operations))
+	for i, op := range e.Operations {
+		msgs[i] = fmt.Sprintf("%s operation is not allowed: %s", op.Safety, op.SQL)
+	}
+	return "migu: " + strings.Join(msgs, "\nmigu: ")
+}
+
+func (k OperationKind) defaultSafety() Safety {
+	switch k {
+	case OperationCreateTable, OperationAddColumn, OperationDropIndex, OperationSeed, OperationModifyComment:
+		return SafetySafe
+	case OperationDropTable, OperationDropColumn, OperationArchive:
+		return SafetyDestructive
+	case OperationPruneSeed:
+		return SafetyDataLossy
+	}
+	return SafetyLocking
+}
+
+// addColumnSafety returns the safety class of adding the column f.
+func addColumnSafety(f *field) Safety {
+	if !f.Nullable && f.Default == "" && !f.AutoIncrement {
+		// The existing rows must be filled by the implicit default value.
+		return SafetyLocking
+	}
+	return SafetySafe
+}
+
+// modifyColumnSafety returns the safety class of modifying the column from oldField to newField.
+func modifyColumnSafety(oldField, newField *field) Safety {
+	if oldField.Nullable && !newField.Nullable {
+		return SafetyDataLossy
+	}
+	if isNarrowingType(oldField.Type, newField.Type) {
+		return SafetyDataLossy
+	}
+	return SafetyLocking
+}
+
+var (
+	integerTypeRanks = map[string]int{
+		"TINYINT":   1,
+		"SMALLINT":  2,
+		"MEDIUMINT": 3,
+		"INT":       4,
+		"INTEGER":   4,
+		"BIGINT":    5,
+		"INT64":     5,
+	}
+	textTypeRanks = map[string]int{
+		"CHAR":       1,
+		"VARCHAR":    1,
+		"STRING":     1,
+		"TINYTEXT":   2,
+		"TEXT":       3,
+		"MEDIUMTEXT": 4,
+		"LONGTEXT":   5,
+	}
+	binaryTypeRanks = map[string]int{
+		"BINARY":     1,
+		"VARBINARY":  1,
+		"BYTES":      1,
+		"TINYBLOB":   2,
+		"BLOB":       3,
+		"MEDIUMBLOB": 4,
+		"LONGBLOB":   5,
+	}
+)
+
+// isNarrowingType returns whether the values of oldType may not fit in newType.
+func isNarrowingType(oldType, newType string) bool {
+	oldBase, oldSize, oldUnsigned := splitColumnType(oldType)
+	newBase, newSize, newUnsigned := splitColumnType(newType)
+	for _, ranks := range []map[string]int{integerTypeRanks, textTypeRanks, binaryTypeRanks} {
+		oldRank, oldOK := ranks[oldBase]
+		newRank, newOK := ranks[newBase]
+		if !oldOK && !newOK {
+			continue
+		}
+		if oldOK != newOK {
+			return true
+		}
+		if oldRank != newRank {
+			return newRank < oldRank
+		}
+		if oldUnsigned != newUnsigned {
+			return true
+		}
+		if _, ok := integerTypeRanks[oldBase]; ok {
+			// The display width of the integer types does not limit the values.
+			return false
+		}
+		return oldSize > 0 && (newSize > 0 && newSize < oldSize)
+	}
+	if oldBase != newBase {
+		return true
+	}
+	if _, ok := fixedPointTypes[oldBase]; ok {
+		oldPrecision, oldScale := fixedPointDigits(oldType)
+		newPrecision, newScale := fixedPointDigits(newType)
+		return newScale < oldScale || newPrecision-newScale < oldPrecision-oldScale
+	}
+	if _, ok := fractionalSecondsTypes[oldBase]; ok {
+		// The fractional seconds precision is 0 if omitted.
+		return newSize < oldSize
+	}
+	return oldSize > 0 && newSize > 0 && newSize < oldSize
+}
+
+var (
+	fixedPointTypes = map[string]struct{}{
+		"DECIMAL": {},
+		"NUMERIC": {},
+		"DEC":     {},
+		"FIXED":   {},
+	}
+	fractionalSecondsTypes = map[string]struct{}{
+		"DATETIME":  {},
+		"TIME":      {},
+		"TIMESTAMP": {},
+	}
+)
+
+// fixedPointDigits returns the precision and the scale of the fixed-point type such as "DECIMAL(10,2)".
+// The precision is 10 and the scale is 0 if they are omitted as MySQL does.
+func fixedPointDigits(typ string) (precision, scale int) {
+	precision = 10
+	start := strings.IndexByte(typ, '(')
+	if start < 0 {
+		return precision, scale
+	}
+	end := strings.IndexByte(typ[start:], ')')
+	if end < 0 {
+		return precision, scale
+	}
+	params := strings.Split(typ[start+1:start+end], ",")
+	if p, err := strconv.Atoi(strings.TrimSpace(params[0])); err == nil {
+		precision = p
+	}
+	if len(params) > 1 {
+		scale, _ = strconv.Atoi(strings.TrimSpace(params[1]))
+	}
+	return precision, scale
+}
+
+// splitColumnType splits the column type such as "VARCHAR(255)" into the base type name and the size.
+// The size is 0 if it is not specified, and -1 if it is "MAX".
+func splitColumnType(typ string) (base string, size int, unsigned bool) {
+	typ = strings.ToUpper(strings.TrimSpace(typ))
+	if i := strings.Index(typ, " UNSIGNED"); i >= 0 {
+		typ, unsigned = typ[:i], true
+	}
+	base = typ
+	if start := strings.IndexByte(typ, '('); start >= 0 {
+		base = typ[:start]
+		if end := strings.IndexByte(typ[start:], ')'); end >= 0 {
+			param := typ[start+1 : start+end]
+			if i := strings.IndexByte(param, ','); i >= 0 {
+				param = param[:i]
+			}
+			if param == "MAX" {
+				size = -1
+			} else {
+				size, _ = strconv.Atoi(strings.TrimSpace(param))
+			}
+		}
+	}
+	if size < 0 {
+		size = int(^uint(0) >> 1)
+	}
+	return base, size, unsigned
+}
+
+func (o *option) checkSafety(ops []Operation) error {
+	if len(o.disallowedSafety) == 0 {
+		return nil
+	}
+	var disallowed []Operation
+	for _, op := range ops {
+		if _, ok := o.disallowedSafety[op.Safety]; ok {
+			disallowed = append(disallowed, op)
+		}
+	}
+	if len(disallowed) > 0 {
+		return &SafetyError{Operations: disallowed}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package migu
+
+import (
+	"sort"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// Schema is the model of the database schema.
+type Schema struct {
+	Tables []*Table `json:"tables" yaml:"tables"`
+}
+
+// Table is the model of the database table.
+type Table struct {
+	Name      string    `json:"name" yaml:"name"`
+	Engine    string    `json:"engine,omitempty" yaml:"engine,omitempty"`
+	Charset   string    `json:"charset,omitempty" yaml:"charset,omitempty"`
+	Collate   string    `json:"collate,omitempty" yaml:"collate,omitempty"`
+	RowFormat string    `json:"rowFormat,omitempty" yaml:"rowFormat,omitempty"`
+	Option    string    `json:"option,omitempty" yaml:"option,omitempty"`
+	Columns   []*Column `json:"columns" yaml:"columns"`
+	Indexes   []*Index  `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+}
+
+// Column is the model of the column of the database table.
+type Column struct {
+	Name string `json:"name" yaml:"name"`
+
+	// Type is the column type of the database. (e.g. "VARCHAR(255)")
+	Type string `json:"type" yaml:"type"`
+
+	// GoType is the type of Go's struct field for the column. (e.g. "string")
+	GoType string `json:"goType,omitempty" yaml:"goType,omitempty"`
+
+	Nullable      bool   `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	PrimaryKey    bool   `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	AutoIncrement bool   `json:"autoIncrement,omitempty" yaml:"autoIncrement,omitempty"`
+	Default       string `json:"default,omitempty" yaml:"default,omitempty"`
+	Extra         string `json:"extra,omitempty" yaml:"extra,omitempty"`
+	Comment       string `json:"comment,omitempty" yaml:"comment,omitempty"`
+
+	// References is the column of the other table that the column refers to by `fk` tag. (e.g. "user.id")
+	References string `json:"references,omitempty" yaml:"references,omitempty"`
+}
+
+// Index is the model of the index of the database table.
+type Index struct {
+	Name    string   `json:"name" yaml:"name"`
+	Columns []string `json:"columns" yaml:"columns"`
+	Unique  bool     `json:"unique,omitempty" yaml:"unique,omitempty"`
+}
+
+// ParseSchema returns the schema that is defined by Go's structs.
+// Go's structs are read in the same way as Diff reads filename and src.
+func ParseSchema(d dialect.Dialect, filename string, src interface{}) (*Schema, error) {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	m, err := makeTableMap(d, structASTMap, newOption())
+	if err != nil {
+		return nil, err
+	}
+	return newSchema(m), nil
+}
+
+// InspectSchema returns the current schema of the database.
+func InspectSchema(d dialect.Dialect, opts ...Option) (*Schema, error) {
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return nil, err
+	}
+	m, err := makeTableMapFromColumnSchemas(d, tableMap, newOption(opts...))
+	if err != nil {
+		return nil, err
+	}
+	return newSchema(m), nil
+}
+
+// Table returns the table that has the name.
+// It returns nil if the table is not found.
+func (s *Schema) Table(name string) *Table {
+	for _, t := range s.Tables {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// Column returns the column that has the name.
+// It returns nil if the column is not found.
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// PrimaryKeys returns the names of the primary key columns.
+func (t *Table) PrimaryKeys() []string {
+	var pks []string
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			pks = append(pks, c.Name)
+		}
+	}
+	return pks
+}
+
+func newSchema(tableMap map[string]*table) *Schema {
+	names := make([]string, 0, len(tableMap))
+	for name := range tableMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schema := &Schema{
+		Tables: make([]*Table, 0, len(names)),
+	}
+	for _, name := range names {
+		schema.Tables = append(schema.Tables, newTable(name, tableMap[name]))
+	}
+	return schema
+}
+
+func newTable(name string, tbl *table) *Table {
+	t := &Table{
+		Name:      name,
+		Engine:    tbl.Options.Engine,
+		Charset:   tbl.Options.Charset,
+		Collate:   tbl.Options.Collate,
+		RowFormat: tbl.Options.RowFormat,
+		Option:    tbl.Option,
+		Columns:   make([]*Column, len(tbl.Fields)),
+	}
+	for i, f := range tbl.Fields {
+		t.Columns[i] = &Column{
+			Name:          f.Column,
+			Type:          f.Type,
+			GoType:        f.GoType,
+			Nullable:      f.Nullable,
+			PrimaryKey:    f.PrimaryKey,
+			AutoIncrement: f.AutoIncrement,
+			Default:       f.Default,
+			Extra:         f.Extra,
+			Comment:       f.Comment,
+			References:    f.ForeignKey,
+		}
+	}
+	indexes, _ := makeIndexes(nil, tbl.Fields)
+	for _, index := range indexes {
+		t.Indexes = append(t.Indexes, &Index{
+			Name:    index.Name,
+			Columns: index.Columns,
+			Unique:  index.Unique,
+		})
+	}
+	return t
+}
@@ -0,0 +1,263 @@
+package migu
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// seed is the rows of the table that are declared by the annotated slice of the struct.
+//
+//	//+migu
+//	var roles = []Role{
+//		{ID: 1, Name: "admin"},
+//	}
+type seed struct {
+	Pos      token.Position
+	TypeName string
+	Table    string
+	Rows     [][]*ast.KeyValueExpr
+}
+
+func loadSeeds(filename string, src interface{}) ([]*seed, error) {
+	filenames, err := sourceFilenames(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	var seeds []*seed
+	for _, filename := range filenames {
+		s, err := parseSeeds(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, s...)
+	}
+	return seeds, nil
+}
+
+func loadSeedsFS(fsys fs.FS, patterns []string) ([]*seed, error) {
+	filenames, err := globFS(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+	var seeds []*seed
+	for _, filename := range filenames {
+		src, err := fs.ReadFile(fsys, filename)
+		if err != nil {
+			return nil, err
+		}
+		s, err := parseSeeds(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, s...)
+	}
+	return seeds, nil
+}
+
+func parseSeeds(filename string, src interface{}) ([]*seed, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var seeds []*seed
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range d.Specs {
+			a, err := parseSpecAnnotation(fset, d, spec)
+			if err != nil {
+				return nil, err
+			}
+			if a == nil {
+				continue
+			}
+			for _, value := range spec.(*ast.ValueSpec).Values {
+				s, err := newSeed(fset, a, value)
+				if err != nil {
+					return nil, err
+				}
+				seeds = append(seeds, s)
+			}
+		}
+	}
+	return seeds, nil
+}
+
+func newSeed(fset *token.FileSet, a *annotation, expr ast.Expr) (*seed, error) {
+	pos := fset.Position(expr.Pos())
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return nil, fmt.Errorf("migu: %v: seed must be a slice literal of the struct", pos)
+	}
+	typ, ok := lit.Type.(*ast.ArrayType)
+	if !ok || typ.Len != nil {
+		return nil, fmt.Errorf("migu: %v: seed must be a slice literal of the struct", pos)
+	}
+	ident, ok := typ.Elt.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("migu: %v: seed must be a slice literal of the struct", pos)
+	}
+	s := &seed{
+		Pos:      pos,
+		TypeName: ident.Name,
+		Table:    a.Table,
+	}
+	for _, elt := range lit.Elts {
+		row, ok := elt.(*ast.CompositeLit)
+		if !ok {
+			return nil, fmt.Errorf("migu: %v: seed row must be a struct literal", fset.Position(elt.Pos()))
+		}
+		var values []*ast.KeyValueExpr
+		for _, e := range row.Elts {
+			kv, ok := e.(*ast.KeyValueExpr)
+			if !ok {
+				return nil, fmt.Errorf("migu: %v: seed row must be a struct literal with the field names", fset.Position(e.Pos()))
+			}
+			values = append(values, kv)
+		}
+		s.Rows = append(s.Rows, values)
+	}
+	return s, nil
+}
+
+// seedOperations returns the operations to upsert the seed rows.
+// The operations are ordered by the table name, and by the order of the declaration in each table.
+// The rows are upserted unconditionally without reading the existing rows, because the upsert is idempotent and
+// comparing the SQL literals with the values that are read from the database is not reliable.
+// Therefore, the plan is never empty while the seed data is declared.
+func seedOperations(d dialect.Dialect, seeds []*seed, structASTMap map[string]*structAST, structMap map[string]*table, opt *option) ([]Operation, error) {
+	if len(seeds) == 0 {
+		return nil, nil
+	}
+	seeder, ok := d.(dialect.Seeder)
+	if !ok {
+		return nil, fmt.Errorf("migu: the dialect does not support the seed data")
+	}
+	tableSeeds := map[string][]*seed{}
+	for _, s := range seeds {
+		name := s.Table
+		if name == "" {
+			for n, st := range structASTMap {
+				if st.TypeName == s.TypeName {
+					if st.Annotation.Shards > 0 {
+						return nil, fmt.Errorf("migu: %v: %s is sharded, so the table of the seed must be given by table annotation", s.Pos, s.TypeName)
+					}
+					name = n
+					break
+				}
+			}
+		}
+		if structMap[name] == nil {
+			return nil, fmt.Errorf("migu: %v: %s is not a table", s.Pos, s.TypeName)
+		}
+		tableSeeds[name] = append(tableSeeds[name], s)
+	}
+	names := make([]string, 0, len(tableSeeds))
+	for name := range tableSeeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var ops []Operation
+	for _, name := range names {
+		tbl := structMap[name]
+		fieldMap := make(map[string]*field, len(tbl.Fields))
+		var pkColumns []string
+		for _, f := range tbl.Fields {
+			fieldMap[f.Name] = f
+			if f.PrimaryKey {
+				pkColumns = append(pkColumns, f.Column)
+			}
+		}
+		if len(pkColumns) == 0 {
+			return nil, fmt.Errorf("migu: %v: table `%s' for the seed has no primary key", tableSeeds[name][0].Pos, name)
+		}
+		var (
+			upserts []Operation
+			keys    [][]string
+		)
+		for _, s := range tableSeeds[name] {
+			for _, row := range s.Rows {
+				var columns, values []string
+				valueMap := map[string]string{}
+				for _, kv := range row {
+					key, ok := kv.Key.(*ast.Ident)
+					if !ok || fieldMap[key.Name] == nil {
+						return nil, fmt.Errorf("migu: %v: unknown field of %s: %v", s.Pos, s.TypeName, kv.Key)
+					}
+					value, err := seedValue(d, kv.Value)
+					if err != nil {
+						return nil, fmt.Errorf("migu: %v: %v", s.Pos, err)
+					}
+					column := fieldMap[key.Name].Column
+					columns = append(columns, column)
+					values = append(values, value)
+					valueMap[column] = value
+				}
+				key := make([]string, len(pkColumns))
+				for i, pk := range pkColumns {
+					v, ok := valueMap[pk]
+					if !ok {
+						return nil, fmt.Errorf("migu: %v: seed row of %s has no value of the primary key `%s'", s.Pos, s.TypeName, pk)
+					}
+					key[i] = v
+				}
+				keys = append(keys, key)
+				upserts = append(upserts, newOperations(OperationSeed, name, "", seeder.UpsertSQL(name, columns, values, pkColumns), nil)...)
+			}
+		}
+		if opt.seedPrune {
+			ops = append(ops, newOperations(OperationPruneSeed, name, "", seeder.PruneSQL(name, pkColumns, keys), nil)...)
+		}
+		ops = append(ops, upserts...)
+	}
+	return ops, nil
+}
+
+// seedValue returns the SQL literal of the expression.
+func seedValue(d dialect.Dialect, expr ast.Expr) (string, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case token.INT, token.FLOAT:
+			return e.Value, nil
+		case token.STRING:
+			s, err := strconv.Unquote(e.Value)
+			if err != nil {
+				return "", err
+			}
+			return d.QuoteString(s), nil
+		case token.CHAR:
+			s, err := strconv.Unquote(e.Value)
+			if err != nil {
+				return "", err
+			}
+			return strconv.Itoa(int([]rune(s)[0])), nil
+		}
+	case *ast.Ident:
+		switch e.Name {
+		case "true", "false":
+			return strings.ToUpper(e.Name), nil
+		case "nil":
+			return "NULL", nil
+		}
+	case *ast.UnaryExpr:
+		if lit, ok := e.X.(*ast.BasicLit); ok && e.Op == token.SUB && (lit.Kind == token.INT || lit.Kind == token.FLOAT) {
+			return "-" + lit.Value, nil
+		}
+	case *ast.ParenExpr:
+		return seedValue(d, e.X)
+	}
+	return "", fmt.Errorf("unsupported seed value: %s", types.ExprString(expr))
+}
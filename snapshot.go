@@ -0,0 +1,232 @@
+package migu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
+)
+
+// Snapshot is a serializable snapshot of the database schema.
+type Snapshot struct {
+	Tables []SnapshotTable `json:"tables" yaml:"tables"`
+}
+
+// SnapshotTable is a table in the Snapshot.
+type SnapshotTable struct {
+	Name      string           `json:"name" yaml:"name"`
+	Engine    string           `json:"engine,omitempty" yaml:"engine,omitempty"`
+	Charset   string           `json:"charset,omitempty" yaml:"charset,omitempty"`
+	Collate   string           `json:"collate,omitempty" yaml:"collate,omitempty"`
+	RowFormat string           `json:"rowFormat,omitempty" yaml:"rowFormat,omitempty"`
+	Option    string           `json:"option,omitempty" yaml:"option,omitempty"`
+	Columns   []SnapshotColumn `json:"columns" yaml:"columns"`
+}
+
+// SnapshotColumn is a column of the table in the Snapshot.
+type SnapshotColumn struct {
+	Name          string   `json:"name" yaml:"name"`
+	Type          string   `json:"type" yaml:"type"`
+	Nullable      bool     `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	PrimaryKey    bool     `json:"primaryKey,omitempty" yaml:"primaryKey,omitempty"`
+	AutoIncrement bool     `json:"autoIncrement,omitempty" yaml:"autoIncrement,omitempty"`
+	Default       string   `json:"default,omitempty" yaml:"default,omitempty"`
+	Extra         string   `json:"extra,omitempty" yaml:"extra,omitempty"`
+	Comment       string   `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Indexes       []string `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+	Uniques       []string `json:"uniques,omitempty" yaml:"uniques,omitempty"`
+	References    string   `json:"references,omitempty" yaml:"references,omitempty"`
+}
+
+// TakeSnapshot returns the snapshot of the current database schema.
+func TakeSnapshot(d dialect.Dialect, opts ...Option) (*Snapshot, error) {
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return nil, err
+	}
+	m, err := makeTableMapFromColumnSchemas(d, tableMap, newOption(opts...))
+	if err != nil {
+		return nil, err
+	}
+	return newSnapshot(m), nil
+}
+
+// DiffSnapshot is like Diff, but compares Go's structs with snapshot instead of the database.
+// snapshot is typically taken by TakeSnapshot and saved by SaveSnapshot.
+// d is used only for generating SQLs.
+func DiffSnapshot(d dialect.Dialect, snapshot *Snapshot, filename string, src interface{}, opts ...Option) ([]string, error) {
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	o := newOption(opts...)
+	structMap, err := makeTableMap(d, structASTMap, o)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := diffTables(d, snapshot.tableMap(), structMap, o)
+	if err != nil {
+		return nil, err
+	}
+	return o.operationSQLs(ops), nil
+}
+
+// WriteSnapshot writes snapshot to w in format.
+// The supported formats are "json" and "yaml".
+func WriteSnapshot(w io.Writer, snapshot *Snapshot, format string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshot)
+	case "yaml":
+		return yaml.NewEncoder(w).Encode(snapshot)
+	}
+	return fmt.Errorf("migu: unsupported snapshot format: %s", format)
+}
+
+// ReadSnapshot reads the snapshot in format from r.
+// The supported formats are "json" and "yaml".
+func ReadSnapshot(r io.Reader, format string) (*Snapshot, error) {
+	var snapshot Snapshot
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		if err := yaml.NewDecoder(r).Decode(&snapshot); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("migu: unsupported snapshot format: %s", format)
+	}
+	return &snapshot, nil
+}
+
+// LoadSnapshot reads the snapshot from the file.
+// The format is detected from the extension of filename.
+func LoadSnapshot(filename string) (*Snapshot, error) {
+	format, err := snapshotFormat(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f, format)
+}
+
+// SaveSnapshot writes the snapshot to the file.
+// The format is detected from the extension of filename.
+func SaveSnapshot(filename string, snapshot *Snapshot) (err error) {
+	format, err := snapshotFormat(filename)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}()
+	return WriteSnapshot(f, snapshot, format)
+}
+
+func snapshotFormat(filename string) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		return "json", nil
+	case ".yaml", ".yml":
+		return "yaml", nil
+	default:
+		return "", fmt.Errorf("migu: unsupported snapshot file extension: %s", ext)
+	}
+}
+
+func newSnapshot(tableMap map[string]*table) *Snapshot {
+	names := make([]string, 0, len(tableMap))
+	for name := range tableMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	snapshot := &Snapshot{
+		Tables: make([]SnapshotTable, 0, len(names)),
+	}
+	for _, name := range names {
+		tbl := tableMap[name]
+		st := SnapshotTable{
+			Name:      name,
+			Engine:    tbl.Options.Engine,
+			Charset:   tbl.Options.Charset,
+			Collate:   tbl.Options.Collate,
+			RowFormat: tbl.Options.RowFormat,
+			Option:    tbl.Option,
+			Columns:   make([]SnapshotColumn, len(tbl.Fields)),
+		}
+		for i, f := range tbl.Fields {
+			st.Columns[i] = SnapshotColumn{
+				Name:          f.Column,
+				Type:          f.Type,
+				Nullable:      f.Nullable,
+				PrimaryKey:    f.PrimaryKey,
+				AutoIncrement: f.AutoIncrement,
+				Default:       f.Default,
+				Extra:         f.Extra,
+				Comment:       f.Comment,
+				Indexes:       f.Indexes(),
+				Uniques:       f.UniqueIndexes(),
+				References:    f.ForeignKey,
+			}
+		}
+		snapshot.Tables = append(snapshot.Tables, st)
+	}
+	return snapshot
+}
+
+func (s *Snapshot) tableMap() map[string]*table {
+	m := make(map[string]*table, len(s.Tables))
+	for _, st := range s.Tables {
+		tbl := &table{
+			Options: dialect.TableOptions{
+				Engine:    st.Engine,
+				Charset:   st.Charset,
+				Collate:   st.Collate,
+				RowFormat: st.RowFormat,
+			},
+			Option: st.Option,
+			Fields: make([]*field, len(st.Columns)),
+		}
+		for i, c := range st.Columns {
+			tbl.Fields[i] = &field{
+				Table:         st.Name,
+				Name:          stringutil.ToUpperCamelCase(c.Name),
+				Type:          c.Type,
+				Column:        c.Name,
+				Comment:       c.Comment,
+				RawIndexes:    c.Indexes,
+				RawUniques:    c.Uniques,
+				PrimaryKey:    c.PrimaryKey,
+				AutoIncrement: c.AutoIncrement,
+				Default:       c.Default,
+				Extra:         c.Extra,
+				Nullable:      c.Nullable,
+				ForeignKey:    c.References,
+			}
+		}
+		m[st.Name] = tbl
+	}
+	return m
+}
@@ -0,0 +1,97 @@
+package migu
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// sqlDumpHeader is the header of the files written by DumpSQLDir.
+const sqlDumpHeader = "-- Code generated by migu. DO NOT EDIT.\n"
+
+// DumpSQLDir writes the CREATE TABLE statement of each table in the database to "<table>.sql" file in dir.
+//
+// The files are only rewritten if their contents are changed, and the files
+// of the tables that no longer exist in the database are removed, so the
+// directory can be kept in a version control system to review the schema.
+func DumpSQLDir(d dialect.Dialect, dir string, opts ...Option) error {
+	o := newOption(opts...)
+	tableMap, err := getTableMap(d)
+	if err != nil {
+		return err
+	}
+	if err := o.filterTableMap(tableMap); err != nil {
+		return err
+	}
+	m, err := makeTableMapFromColumnSchemas(d, tableMap, o)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	written := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		filename := filepath.Join(dir, name+".sql")
+		written[filename] = struct{}{}
+		if err := writeFileIfChanged(filename, tableSQL(d, name, m[name])); err != nil {
+			return err
+		}
+	}
+	return removeStaleFiles(filepath.Join(dir, "*.sql"), sqlDumpHeader, written)
+}
+
+func tableSQL(d dialect.Dialect, name string, tbl *table) []byte {
+	sqls := createTableSQL(d, name, tbl)
+	indexes, _ := makeIndexes(nil, tbl.Fields)
+	for _, index := range indexes {
+		sqls = append(sqls, d.CreateIndexSQL(index.ToIndex())...)
+	}
+	var buf bytes.Buffer
+	buf.WriteString(sqlDumpHeader)
+	buf.WriteString("\n")
+	buf.WriteString(strings.Join(sqls, ";\n\n"))
+	buf.WriteString(";\n")
+	return buf.Bytes()
+}
+
+// removeStaleFiles removes the files that match pattern and start with header, except for the written files.
+func removeStaleFiles(pattern, header string, written map[string]struct{}) error {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	for _, filename := range files {
+		if _, ok := written[filename]; ok {
+			continue
+		}
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(b, []byte(header)) {
+			continue
+		}
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFileIfChanged(filename string, b []byte) error {
+	if old, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(old, b) {
+		return nil
+	}
+	return ioutil.WriteFile(filename, b, 0644)
+}
@@ -0,0 +1,50 @@
+package migu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SQLFileMetadata is the provenance of the plan that is written into the header of the SQL file by WriteSQL.
+type SQLFileMetadata struct {
+	// GeneratedAt is the time when the plan is generated. The current time is used if it is zero.
+	GeneratedAt time.Time
+
+	// Source is the file or the directory of Go's structs.
+	Source string
+
+	// Revision is the revision of the source in the version control system, such as the commit hash of git.
+	Revision string
+}
+
+// WriteSQL writes the SQLs of the plan to w as the SQL file.
+// The file starts with the header comment that has the time of generation, the source, the revision and the fingerprint of the schema,
+// so that the migration carries its provenance when it is checked into a repository.
+// The empty fields of metadata are omitted from the header.
+func (p *MigrationPlan) WriteSQL(w io.Writer, metadata SQLFileMetadata) error {
+	bw := bufio.NewWriter(w)
+	generatedAt := metadata.GeneratedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	fmt.Fprintf(bw, "-- Generated by migu at %s.\n", generatedAt.Format(time.RFC3339))
+	if metadata.Source != "" {
+		fmt.Fprintf(bw, "-- Source: %s\n", metadata.Source)
+	}
+	if metadata.Revision != "" {
+		fmt.Fprintf(bw, "-- Revision: %s\n", metadata.Revision)
+	}
+	if p.Fingerprint != "" {
+		fmt.Fprintf(bw, "-- Fingerprint: %s\n", p.Fingerprint)
+	}
+	sqls := p.SQL()
+	if len(sqls) == 0 {
+		fmt.Fprint(bw, "\n-- No changes.\n")
+	}
+	for _, sql := range sqls {
+		fmt.Fprintf(bw, "\n%s;\n", sql)
+	}
+	return bw.Flush()
+}
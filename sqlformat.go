@@ -0,0 +1,159 @@
+package migu
+
+import (
+	"strings"
+)
+
+// KeywordCase represents the letter case of SQL keywords in the generated SQLs.
+type KeywordCase int
+
+const (
+	KeywordUpper KeywordCase = iota
+	KeywordLower
+)
+
+type sqlFormat struct {
+	keywordCase    KeywordCase
+	semicolon      bool
+	alterSpecLines bool
+	maxLineWidth   int
+}
+
+func (f *sqlFormat) isDefault() bool {
+	return *f == sqlFormat{}
+}
+
+func (f *sqlFormat) format(sql string) string {
+	if f.alterSpecLines {
+		sql = splitAlterSpecs(sql)
+	}
+	if f.keywordCase == KeywordLower {
+		sql = lowerKeywords(sql)
+	}
+	if f.maxLineWidth > 0 {
+		lines := strings.Split(sql, "\n")
+		var wrapped []string
+		for _, line := range lines {
+			wrapped = append(wrapped, wrapLine(line, f.maxLineWidth)...)
+		}
+		sql = strings.Join(wrapped, "\n")
+	}
+	if f.semicolon {
+		sql += ";"
+	}
+	return sql
+}
+
+// quotedMask returns the mask that indicates whether each byte of sql is a part of a quoted string or identifier.
+func quotedMask(sql string) []bool {
+	mask := make([]bool, len(sql))
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote == 0:
+			if c == '\'' || c == '`' || c == '"' {
+				quote = c
+				mask[i] = true
+			}
+		case c == '\\' && quote != '`' && i+1 < len(sql):
+			mask[i], mask[i+1] = true, true
+			i++
+		default:
+			mask[i] = true
+			if c == quote {
+				quote = 0
+			}
+		}
+	}
+	return mask
+}
+
+// lowerKeywords converts the unquoted words that consist of upper case letters to lower case.
+func lowerKeywords(sql string) string {
+	mask := quotedMask(sql)
+	b := []byte(sql)
+	for i := 0; i < len(b); {
+		if mask[i] || !isWordByte(b[i]) {
+			i++
+			continue
+		}
+		j := i
+		upper := true
+		for ; j < len(b) && !mask[j] && isWordByte(b[j]); j++ {
+			if 'a' <= b[j] && b[j] <= 'z' {
+				upper = false
+			}
+		}
+		if upper {
+			copy(b[i:j], strings.ToLower(string(b[i:j])))
+		}
+		i = j
+	}
+	return string(b)
+}
+
+func isWordByte(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '_'
+}
+
+// splitAlterSpecs puts each specification of the ALTER TABLE statement on its own line.
+func splitAlterSpecs(sql string) string {
+	const prefix = "ALTER TABLE "
+	if !strings.HasPrefix(sql, prefix) {
+		return sql
+	}
+	mask := quotedMask(sql)
+	// Find the end of the table name.
+	i := len(prefix)
+	for ; i < len(sql); i++ {
+		if sql[i] == ' ' && !mask[i] {
+			break
+		}
+	}
+	if i >= len(sql) {
+		return sql
+	}
+	var specs []string
+	depth, start := 0, i+1
+	for j := start; j < len(sql); j++ {
+		if mask[j] {
+			continue
+		}
+		switch sql[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				specs = append(specs, strings.TrimSpace(sql[start:j]))
+				start = j + 1
+			}
+		}
+	}
+	specs = append(specs, strings.TrimSpace(sql[start:]))
+	return sql[:i] + "\n  " + strings.Join(specs, ",\n  ")
+}
+
+// wrapLine wraps line at the unquoted spaces so that each line is not longer than width as possible.
+func wrapLine(line string, width int) []string {
+	var lines []string
+	indent := line[:len(line)-len(strings.TrimLeft(line, " "))] + "    "
+	for len(line) > width {
+		mask := quotedMask(line)
+		i := -1
+		for j := width; j > len(indent); j-- {
+			if line[j] == ' ' && !mask[j] {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			break
+		}
+		lines = append(lines, line[:i])
+		line = indent + strings.TrimLeft(line[i+1:], " ")
+	}
+	return append(lines, line)
+}
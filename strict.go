@@ -0,0 +1,103 @@
+package migu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AmbiguityError is the ambiguous or lossy mapping of a struct field to the column that is rejected by WithStrict option.
+type AmbiguityError struct {
+	Table   string
+	Column  string
+	Message string
+}
+
+func (e *AmbiguityError) Error() string {
+	return fmt.Sprintf("migu: %s.%s: %s", e.Table, e.Column, e.Message)
+}
+
+// AmbiguityErrors is a list of *AmbiguityError.
+type AmbiguityErrors []*AmbiguityError
+
+func (e AmbiguityErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// checkAmbiguities returns AmbiguityErrors if the fields of structMap have the ambiguous or lossy mappings.
+// It returns nil unless WithStrict option is given.
+func (o *option) checkAmbiguities(structMap map[string]*table) error {
+	if !o.strict {
+		return nil
+	}
+	names := make([]string, 0, len(structMap))
+	for name := range structMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs AmbiguityErrors
+	for _, name := range names {
+		for _, f := range structMap[name].Fields {
+			for _, msg := range ambiguities(f) {
+				errs = append(errs, &AmbiguityError{
+					Table:   name,
+					Column:  f.Column,
+					Message: msg,
+				})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ambiguities returns the reasons why the mapping of f is ambiguous or lossy.
+func ambiguities(f *field) []string {
+	var msgs []string
+	goType := strings.TrimLeft(f.GoType, "*")
+	typ := baseColumnType(f.Type)
+	if !f.HasType {
+		switch goType {
+		case "int", "uint":
+			msgs = append(msgs, fmt.Sprintf("the size of Go's %s type depends on the platform, but it is mapped to %s type", goType, f.Type))
+		case "float32":
+			if typ != "FLOAT" {
+				msgs = append(msgs, fmt.Sprintf("Go's float32 type is mapped to %s type that has the different precision", f.Type))
+			}
+		default:
+			if f.Type == strings.ToUpper(goType) {
+				msgs = append(msgs, fmt.Sprintf("Go's %s type is not mapped to any column type", goType))
+			}
+		}
+	}
+	if f.AutoIncrement {
+		if _, ok := integerTypes[typ]; !ok && typ != "INT64" {
+			msgs = append(msgs, fmt.Sprintf("autoincrement is specified for the column of %s type", f.Type))
+		}
+		if f.Default != "" {
+			msgs = append(msgs, "both autoincrement and default are specified")
+		}
+	}
+	if f.PrimaryKey && f.Nullable {
+		msgs = append(msgs, "primary key column is nullable")
+	}
+	if f.Backfill != "" && (f.Nullable || f.Default != "") {
+		msgs = append(msgs, "backfill is never used for the column that is nullable or has the default value")
+	}
+	return msgs
+}
+
+// baseColumnType returns the column type without the size and the attributes. (e.g. "BIGINT" of "bigint(20) unsigned")
+func baseColumnType(columnType string) string {
+	typ := strings.ToUpper(strings.TrimSpace(columnType))
+	if i := strings.IndexAny(typ, "( "); i >= 0 {
+		typ = typ[:i]
+	}
+	return typ
+}
@@ -0,0 +1,79 @@
+package migu
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// compileTablePatterns compiles patterns that must match the whole table name.
+func compileTablePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("migu: invalid table pattern: %v", err)
+		}
+		res[i] = re
+	}
+	return res, nil
+}
+
+func matchTable(res []*regexp.Regexp, name string) bool {
+	for _, re := range res {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// tableFilter returns the function that reports whether the table of name is included and is not excluded by the options.
+// It returns nil if no patterns are given.
+func (o *option) tableFilter() (func(name string) bool, error) {
+	if len(o.includeTables) == 0 && len(o.excludeTables) == 0 {
+		return nil, nil
+	}
+	includes, err := compileTablePatterns(o.includeTables)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := compileTablePatterns(o.excludeTables)
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) bool {
+		return (len(includes) == 0 || matchTable(includes, name)) && !matchTable(excludes, name)
+	}, nil
+}
+
+// filterTableMap removes the tables that are not included or are excluded by the options from tableMap.
+func (o *option) filterTableMap(tableMap map[string][]dialect.ColumnSchema) error {
+	filter, err := o.tableFilter()
+	if err != nil || filter == nil {
+		return err
+	}
+	for name := range tableMap {
+		if !filter(name) {
+			delete(tableMap, name)
+		}
+	}
+	return nil
+}
+
+// filterTables returns the copy of m that has only the tables that are included and are not excluded by the options.
+// It returns m as it is if no patterns are given.
+func (o *option) filterTables(m map[string]*table) (map[string]*table, error) {
+	filter, err := o.tableFilter()
+	if err != nil || filter == nil {
+		return m, err
+	}
+	filtered := make(map[string]*table, len(m))
+	for name, tbl := range m {
+		if filter(name) {
+			filtered[name] = tbl
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,203 @@
+package migu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+	"github.com/naoina/migu/dialect"
+)
+
+// FormatFile returns the source of the file that has the migu tags of the annotated structs in the canonical form,
+// so that the diffs of the model files stay clean across a team. The file is read in the same way as Diff reads filename and src.
+//
+// The options of each tag are ordered as column, type, default, pk, autoincrement, index, unique, fk, null, extra and backfill.
+// The column name derived from the field name is made explicit, the type is uppercased without the display width of the integer types,
+// and the default value is normalized in the same way as Diff compares it. (e.g. "0.00" to "0" and "now()" to "CURRENT_TIMESTAMP")
+// The other struct tags, the fields tagged `migu:"-"` and the embedded fields are left as they are.
+func FormatFile(d dialect.Dialect, filename string, src interface{}) ([]byte, error) {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			annotation, err := parseSpecAnnotation(fset, gd, ts)
+			if err != nil {
+				return nil, err
+			}
+			if annotation == nil {
+				continue
+			}
+			for _, fld := range st.Fields.List {
+				if err := formatFieldTag(d, fld); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatFieldTag rewrites the migu tag of fld in the canonical form.
+func formatFieldTag(d dialect.Dialect, fld *ast.Field) error {
+	if len(fld.Names) == 0 {
+		return nil
+	}
+	pairs := [][2]string{}
+	if fld.Tag != nil {
+		var err error
+		if pairs, err = parseTagPairs(fld.Tag.Value); err != nil {
+			return err
+		}
+	}
+	raw := &field{Name: fld.Names[0].Name}
+	index := -1
+	for i, pair := range pairs {
+		if pair[0] != "migu" {
+			continue
+		}
+		value, err := strconv.Unquote(pair[1])
+		if err != nil {
+			return err
+		}
+		if err := parseStructTag(d, raw, reflect.StructTag(`migu:`+strconv.Quote(value))); err != nil {
+			return err
+		}
+		index = i
+	}
+	if raw.Ignore {
+		return nil
+	}
+	if raw.Column == "" {
+		if raw.Name == "_" {
+			return nil
+		}
+		raw.Column = stringutil.ToSnakeCase(raw.Name)
+	}
+	columnType := raw.Type
+	if columnType == "" {
+		typeName, err := detectTypeName(fld)
+		if err != nil {
+			return err
+		}
+		columnType = strings.TrimLeft(typeName, "*")
+	}
+	tags := []string{tagColumn + ":" + raw.Column}
+	if raw.Type != "" {
+		tags = append(tags, tagType+":"+canonicalColumnType(raw.Type))
+	}
+	if raw.Default != "" {
+		tags = append(tags, tagDefault+":"+normalizeDefault(d.ColumnType(columnType), raw.Default))
+	}
+	if raw.PrimaryKey {
+		tags = append(tags, tagPrimaryKey)
+	}
+	if raw.AutoIncrement {
+		tags = append(tags, tagAutoIncrement)
+	}
+	for _, name := range raw.RawIndexes {
+		tags = append(tags, tagOption(tagIndex, raw.indexOption(name)))
+	}
+	for _, name := range raw.RawUniques {
+		tags = append(tags, tagOption(tagUnique, raw.indexOption(name)))
+	}
+	if raw.ForeignKey != "" {
+		tags = append(tags, tagForeignKey+":"+raw.ForeignKey)
+	}
+	if raw.Nullable {
+		tags = append(tags, tagNull)
+	}
+	if raw.Extra != "" {
+		tags = append(tags, tagExtra+":"+raw.Extra)
+	}
+	if raw.Backfill != "" {
+		tags = append(tags, tagBackfill+":"+raw.Backfill)
+	}
+	value := strconv.Quote(strings.Join(tags, ","))
+	if index < 0 {
+		pairs = append([][2]string{{"migu", value}}, pairs...)
+	} else {
+		pairs[index][1] = value
+	}
+	strs := make([]string, len(pairs))
+	for i, pair := range pairs {
+		strs[i] = pair[0] + ":" + pair[1]
+	}
+	if fld.Tag == nil {
+		fld.Tag = &ast.BasicLit{
+			Kind:     token.STRING,
+			ValuePos: fld.Type.End(),
+		}
+	}
+	fld.Tag.Value = "`" + strings.Join(strs, " ") + "`"
+	return nil
+}
+
+// tagOption returns the option of key that has the value if any. (e.g. "index" or "index:idx_name")
+func tagOption(key, value string) string {
+	if value == "" {
+		return key
+	}
+	return key + ":" + value
+}
+
+// canonicalColumnType returns the column type of the type tag in the canonical form.
+// The name and the attributes are uppercased, the spaces in the size are removed, and the display widths of the integer types are removed
+// in the same way as normalizeColumnType. The parameters that have the quoted values such as ENUM are left as they are.
+// (e.g. "int(11) unsigned" to "INT UNSIGNED", "decimal(10, 2)" to "DECIMAL(10,2)")
+func canonicalColumnType(columnType string) string {
+	typ := strings.TrimSpace(columnType)
+	i := strings.IndexAny(typ, "( ")
+	if i < 0 {
+		return strings.ToUpper(typ)
+	}
+	base, rest := strings.ToUpper(typ[:i]), typ[i:]
+	var params string
+	if rest[0] == '(' {
+		j := strings.LastIndexByte(rest, ')')
+		if j < 0 {
+			return base + rest
+		}
+		params, rest = rest[1:j], rest[j+1:]
+		if !strings.ContainsAny(params, `'"`) {
+			params = strings.Join(strings.Fields(params), "")
+		}
+		if _, ok := integerTypes[base]; ok && !(base == "TINYINT" && params == "1") {
+			params = ""
+		} else {
+			params = "(" + params + ")"
+		}
+	}
+	if attrs := strings.Fields(strings.ToUpper(rest)); len(attrs) > 0 {
+		return base + params + " " + strings.Join(attrs, " ")
+	}
+	return base + params
+}
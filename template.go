@@ -0,0 +1,104 @@
+package migu
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// TemplateData is the data of each table that is passed to the template given by WithTemplate.
+type TemplateData struct {
+	// Table is the name of the table.
+	Table string
+
+	// Comment is the comment of the table.
+	Comment string
+
+	// StructName is the name of Go's struct for the table.
+	StructName string
+
+	// Struct is the declaration of Go's struct that Fprint generates by default, including the "//+migu" annotation.
+	Struct string
+
+	// Enums is the declarations of the named types and their constants for the ENUM columns.
+	Enums string
+
+	Fields []*TemplateField
+}
+
+// TemplateField is the data of each field of Go's struct in TemplateData.
+type TemplateField struct {
+	Name    string
+	Type    string
+	Column  string
+	Comment string
+
+	// Tag is the struct tag without backquotes. (e.g. `migu:"type:bigint"`)
+	Tag string
+}
+
+// Template is the interface of the template given by WithTemplate.
+// *text/template.Template satisfies this interface.
+// Execute is called concurrently for the different tables.
+type Template interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+func newTemplateData(d dialect.Dialect, name, comment string, schemas []dialect.ColumnSchema, o *option) (*TemplateData, error) {
+	decl, err := makeStructAST(d, name, schemas, o)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(o.annotation(name) + "\n")
+	if comment != "" {
+		buf.WriteString(commentLines(comment))
+	}
+	if err := format.Node(&buf, token.NewFileSet(), decl); err != nil {
+		return nil, err
+	}
+	var enums bytes.Buffer
+	for _, e := range o.enumTypes(name, schemas) {
+		for _, decl := range enumDeclsAST(e) {
+			if err := fprintln(&enums, decl); err != nil {
+				return nil, err
+			}
+		}
+	}
+	spec := decl.(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	data := &TemplateData{
+		Table:      name,
+		Comment:    comment,
+		StructName: spec.Name.Name,
+		Struct:     buf.String(),
+		Enums:      enums.String(),
+	}
+	for i, f := range spec.Type.(*ast.StructType).Fields.List {
+		var typ bytes.Buffer
+		if err := format.Node(&typ, token.NewFileSet(), f.Type); err != nil {
+			return nil, err
+		}
+		field := &TemplateField{
+			Name:   f.Names[0].Name,
+			Type:   typ.String(),
+			Column: schemas[i].ColumnName(),
+		}
+		if f.Tag != nil {
+			tag, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			field.Tag = tag
+		}
+		if comment, ok := schemas[i].Comment(); ok {
+			field.Comment = comment
+		}
+		data.Fields = append(data.Fields, field)
+	}
+	return data, nil
+}
@@ -0,0 +1,67 @@
+package migu
+
+import (
+	"context"
+	"strings"
+)
+
+// Tracer is the interface to trace Sync, Diff and Plan, such as by OpenTelemetry.
+//
+// The spans are named "migu.Sync", "migu.Diff", "migu.Plan" and "migu.Apply" for the API calls,
+// "migu.Introspect" for the introspection of the database, and "migu.Exec" for each executed statement.
+type Tracer interface {
+	// StartSpan starts the span that has name and attrs as a child of the span in ctx.
+	// It returns the context that has the started span, and the function to end the span with the error of the traced step.
+	StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, func(err error))
+}
+
+// Attribute is the attribute of the span started by Tracer and the log entry of Logger.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// The keys of the attributes of "migu.Exec" spans.
+const (
+	AttributeOperation = "migu.operation"
+	AttributeTable     = "migu.table"
+	AttributeStatement = "db.statement"
+)
+
+// context returns the context given by WithContext, or the background context.
+func (o *option) context() context.Context {
+	if o.ctx != nil {
+		return o.ctx
+	}
+	return context.Background()
+}
+
+// startSpan starts the span of name by the tracer given by WithTracer.
+// If no tracer is given, it returns ctx and the function that does nothing.
+func (o *option) startSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, func(err error)) {
+	if o.tracer == nil {
+		return ctx, func(error) {}
+	}
+	return o.tracer.StartSpan(ctx, name, attrs...)
+}
+
+// startExecSpan starts "migu.Exec" span of ops that are executed in a single call.
+// The attributes of the operation and the table are of the first operation, and the SQLs are joined by semicolons.
+// The SQLs are omitted from the attributes if WithTraceRedaction option is given.
+func (o *option) startExecSpan(ctx context.Context, ops []Operation) (context.Context, func(err error)) {
+	if o.tracer == nil {
+		return ctx, func(error) {}
+	}
+	attrs := []Attribute{
+		{Key: AttributeOperation, Value: ops[0].Kind.String()},
+		{Key: AttributeTable, Value: ops[0].Table},
+	}
+	if !o.traceRedaction {
+		sqls := make([]string, len(ops))
+		for i, op := range ops {
+			sqls[i] = op.SQL
+		}
+		attrs = append(attrs, Attribute{Key: AttributeStatement, Value: strings.Join(sqls, ";\n")})
+	}
+	return o.startSpan(ctx, "migu.Exec", attrs...)
+}
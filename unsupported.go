@@ -0,0 +1,92 @@
+package migu
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// unsupportedGoType is the Go type that the dialects return for the data types that they do not support.
+const unsupportedGoType = "interface{}"
+
+// UnsupportedColumn is the column of the database that is skipped by WithSkipUnsupportedTypes option
+// because its data type is not supported by the dialect.
+type UnsupportedColumn struct {
+	Table    string
+	Column   string
+	DataType string
+}
+
+func (c *UnsupportedColumn) String() string {
+	return fmt.Sprintf("%s.%s: unsupported data type %s", c.Table, c.Column, c.DataType)
+}
+
+// filterUnsupportedTypes removes the columns that have the data types not supported by the dialect from tableMap
+// if WithSkipUnsupportedTypes option is given. The tables that have no supported columns are also removed.
+// It returns the removed columns, which are also appended to the list of WithSkipUnsupportedTypes in order of the tables and the columns.
+func (o *option) filterUnsupportedTypes(d dialect.Dialect, tableMap map[string][]dialect.ColumnSchema) []*UnsupportedColumn {
+	if !o.skipUnsupportedTypes {
+		return nil
+	}
+	names := make([]string, 0, len(tableMap))
+	for name := range tableMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var unsupported []*UnsupportedColumn
+	for _, name := range names {
+		schemas := tableMap[name]
+		supported := make([]dialect.ColumnSchema, 0, len(schemas))
+		for _, schema := range schemas {
+			if d.GoType(schema.ColumnType(), schema.IsNullable()) != unsupportedGoType {
+				supported = append(supported, schema)
+				continue
+			}
+			unsupported = append(unsupported, &UnsupportedColumn{
+				Table:    name,
+				Column:   schema.ColumnName(),
+				DataType: schema.ColumnType(),
+			})
+		}
+		if len(supported) == 0 {
+			delete(tableMap, name)
+		} else {
+			tableMap[name] = supported
+		}
+	}
+	if o.unsupportedColumns != nil {
+		*o.unsupportedColumns = append(*o.unsupportedColumns, unsupported...)
+	}
+	return unsupported
+}
+
+// removeUnsupportedColumns removes the fields of the unsupported columns from structMap so that
+// Diff does not touch the columns that are skipped by WithSkipUnsupportedTypes option.
+// The structs of the tables that are removed from tableMap are also removed.
+func removeUnsupportedColumns(structMap map[string]*table, tableMap map[string][]dialect.ColumnSchema, unsupported []*UnsupportedColumn) {
+	for _, c := range unsupported {
+		tbl := structMap[c.Table]
+		if tbl == nil {
+			continue
+		}
+		if _, ok := tableMap[c.Table]; !ok {
+			delete(structMap, c.Table)
+			continue
+		}
+		fields := make([]*field, 0, len(tbl.Fields))
+		for _, f := range tbl.Fields {
+			if f.Column != c.Column {
+				fields = append(fields, f)
+			}
+		}
+		structMap[c.Table] = &table{
+			Options:  tbl.Options,
+			Option:   tbl.Option,
+			ReadOnly: tbl.ReadOnly,
+			Fields:   fields,
+			Fset:     tbl.Fset,
+			Pos:      tbl.Pos,
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package migu
 
+import (
+	"sync"
+
+	"github.com/naoina/migu/dialect"
+)
+
 func inStrings(a []string, s string) bool {
 	for _, v := range a {
 		if v == s {
@@ -9,6 +15,51 @@ func inStrings(a []string, s string) bool {
 	return false
 }
 
+// quoteTable returns the quoted table name that is qualified by the dialect if it supports.
+func quoteTable(d dialect.Dialect, name string) string {
+	if q, ok := d.(dialect.TableQuoter); ok {
+		return q.QuoteTable(name)
+	}
+	return d.Quote(name)
+}
+
+// placeholder returns the placeholder of the i-th query parameter of d. i starts from 1.
+func placeholder(d dialect.Dialect, i int) string {
+	if p, ok := d.(dialect.Placeholderer); ok {
+		return p.Placeholder(i)
+	}
+	return "?"
+}
+
 func isSpace(b byte) bool {
 	return b == ' ' || b == '\t'
 }
+
+// parallelDo calls fn with each of 0 to n-1 by at most concurrency goroutines at the same time.
+// It returns the error of the smallest index if any fn returns an error.
+func parallelDo(n, concurrency int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package migu
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/go-stringutil"
+)
+
+// ValidationError is an error in Go's struct that is found by Validate.
+type ValidationError struct {
+	Pos     token.Position
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Pos, e.Message)
+}
+
+// ValidationErrors is a list of *ValidationError.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate validates the annotations and the struct field tags of Go's structs without a database connection.
+// Go's structs are read in the same way as Diff reads filename and src.
+//
+// Validate reports all of the found errors at once as ValidationErrors.
+// It returns nil if no errors are found.
+func Validate(filename string, src interface{}) error {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return err
+	}
+	filenames, err := sourceFilenames(filename, src)
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	v := &validator{
+		fset:   fset,
+		tables: map[string]token.Pos{},
+	}
+	for _, filename := range filenames {
+		f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return err
+		}
+		v.validateFile(f)
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+type validator struct {
+	fset   *token.FileSet
+	tables map[string]token.Pos
+	errs   ValidationErrors
+}
+
+func (v *validator) errorf(pos token.Pos, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{
+		Pos:     v.fset.Position(pos),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// annotationError adds err of the annotation at the position of err, or at pos if err has no position.
+// The error of the grouped declaration that is shared by its specs is added only once.
+func (v *validator) annotationError(pos token.Pos, err error) {
+	var aerr *annotationError
+	if !errors.As(err, &aerr) {
+		v.errorf(pos, "%v", err)
+		return
+	}
+	if n := len(v.errs); n > 0 && v.errs[n-1].Pos == aerr.Pos && v.errs[n-1].Message == aerr.Message {
+		return
+	}
+	v.errs = append(v.errs, &ValidationError{Pos: aerr.Pos, Message: aerr.Message})
+}
+
+func (v *validator) validateFile(f *ast.File) {
+	for _, decl := range f.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			s, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			t, ok := s.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			a, err := parseSpecAnnotation(v.fset, d, s)
+			if err != nil {
+				v.annotationError(s.Pos(), err)
+				continue
+			}
+			if a == nil {
+				continue
+			}
+			names := a.tableNames(s.Name.Name)
+			tables := append([]string(nil), names...)
+			if a.History {
+				for _, name := range names {
+					tables = append(tables, name+historyTableSuffix)
+				}
+			}
+			for _, name := range tables {
+				if pos, exists := v.tables[name]; exists {
+					v.errorf(s.Pos(), "table `%s' is already defined at %v", name, v.fset.Position(pos))
+				} else {
+					v.tables[name] = s.Pos()
+				}
+			}
+			// The shards have the same columns, so that the struct is validated only once.
+			v.validateStruct(names[0], t)
+		}
+	}
+}
+
+func (v *validator) validateStruct(tableName string, t *ast.StructType) {
+	columns := map[string]token.Pos{}
+	var autoIncrement *ast.Field
+	for _, fld := range t.Fields.List {
+		if len(fld.Names) == 0 {
+			continue
+		}
+		typeName, err := detectTypeName(fld)
+		if err != nil {
+			v.errorf(fld.Pos(), "%v", err)
+			continue
+		}
+		f := &field{
+			Table:  tableName,
+			Name:   fld.Names[0].Name,
+			GoType: typeName,
+		}
+		if fld.Tag != nil {
+			s, err := strconv.Unquote(fld.Tag.Value)
+			if err != nil {
+				v.errorf(fld.Tag.Pos(), "%v", err)
+				continue
+			}
+			if err := parseStructTag(nil, f, reflect.StructTag(s)); err != nil {
+				v.errorf(fld.Tag.Pos(), "%v", err)
+				continue
+			}
+		}
+		if f.Ignore || !(ast.IsExported(f.Name) || (f.Name == "_" && f.Column != "")) {
+			continue
+		}
+		if f.Column == "" {
+			f.Column = stringutil.ToSnakeCase(f.Name)
+		}
+		if pos, exists := columns[f.Column]; exists {
+			v.errorf(fld.Pos(), "column `%s' is already defined at %v", f.Column, v.fset.Position(pos))
+		} else {
+			columns[f.Column] = fld.Pos()
+		}
+		if f.PrimaryKey && (f.Nullable || strings.HasPrefix(f.GoType, "*")) {
+			v.errorf(fld.Pos(), "primary key column `%s' cannot be nullable", f.Column)
+		}
+		if f.AutoIncrement {
+			if autoIncrement != nil {
+				v.errorf(fld.Pos(), "table `%s' has multiple autoincrement columns", tableName)
+			}
+			autoIncrement = fld
+		}
+	}
+}
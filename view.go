@@ -0,0 +1,80 @@
+package migu
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// ViewMode represents how Fprint treats the views in the database.
+type ViewMode int
+
+const (
+	// ViewAsTable generates the structs of the views in the same way as the tables.
+	ViewAsTable ViewMode = iota
+
+	// ViewSkip does not generate the structs of the views.
+	ViewSkip
+
+	// ViewReadOnly generates the read-only structs of the views that are annotated with `view:"true"`.
+	// The annotated structs are ignored by Diff, and the CRUD helpers are not generated for them.
+	ViewReadOnly
+)
+
+func (m ViewMode) String() string {
+	switch m {
+	case ViewAsTable:
+		return "table"
+	case ViewSkip:
+		return "skip"
+	case ViewReadOnly:
+		return "readonly"
+	}
+	return fmt.Sprintf("ViewMode(%d)", int(m))
+}
+
+// ParseViewMode returns the ViewMode from its name such as "skip".
+func ParseViewMode(s string) (ViewMode, error) {
+	for _, mode := range []ViewMode{ViewAsTable, ViewSkip, ViewReadOnly} {
+		if strings.EqualFold(s, mode.String()) {
+			return mode, nil
+		}
+	}
+	return ViewAsTable, fmt.Errorf("migu: unknown view mode: %s", s)
+}
+
+// filterViews removes the views from tableMap or marks them as read-only according to the view mode.
+// It does nothing if d does not implement dialect.ViewLister.
+func (o *option) filterViews(d dialect.Dialect, tableMap map[string][]dialect.ColumnSchema) error {
+	if o.viewMode == ViewAsTable {
+		return nil
+	}
+	l, ok := d.(dialect.ViewLister)
+	if !ok {
+		return nil
+	}
+	views, err := l.Views()
+	if err != nil {
+		return err
+	}
+	o.views = map[string]struct{}{}
+	for _, name := range views {
+		if _, ok := tableMap[name]; !ok {
+			continue
+		}
+		switch o.viewMode {
+		case ViewSkip:
+			delete(tableMap, name)
+		case ViewReadOnly:
+			o.views[name] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// isView reports whether the table is the view that is generated as the read-only struct.
+func (o *option) isView(table string) bool {
+	_, ok := o.views[table]
+	return ok
+}
@@ -0,0 +1,67 @@
+package migu
+
+import (
+	"context"
+	"time"
+
+	"github.com/naoina/migu/dialect"
+)
+
+// Watch compares the database schema with Go's structs every interval until ctx is done, and calls onDrift with the plan
+// to synchronize them when the drift appears. Go's structs are read once in the same way as Plan reads filename and src.
+// It is useful as a lightweight schema monitoring daemon inside services.
+//
+// onDrift is called on the first check that finds the drift, and again only if the plan changes.
+// If WithMetrics option is given, the result of each check is reported by ObserveDrift.
+// The schemas are retrieved from the database on each check, so WithSchemaCache option should not be given.
+// The seed data is not watched, because its rows are upserted unconditionally and would always be reported as the drift.
+//
+// Watch returns ctx.Err() when ctx is done, or the error if a check fails.
+func Watch(ctx context.Context, d dialect.Dialect, filename string, src interface{}, interval time.Duration, onDrift func(plan *MigrationPlan), opts ...Option) error {
+	filename, src, err := readSource(filename, src)
+	if err != nil {
+		return err
+	}
+	structASTMap, err := loadStructASTMap(filename, src)
+	if err != nil {
+		return err
+	}
+	o := newOption(append(opts, WithContext(ctx))...)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last []string
+	for {
+		plan, err := planStructASTMap(ctx, d, structASTMap, nil, o)
+		if err != nil {
+			return err
+		}
+		drifted := !plan.IsEmpty()
+		if o.metrics != nil {
+			o.metrics.ObserveDrift(drifted)
+		}
+		sqls := plan.SQL()
+		if !drifted {
+			last = nil
+		} else if !equalStrings(sqls, last) {
+			onDrift(plan)
+			last = sqls
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}